@@ -144,6 +144,17 @@ func (p *Plugin) initializeAPI() {
 	apiRouter.HandleFunc("/settings", p.checkAuth(p.attachUserContext(p.updateSettings), ResponseTypePlain)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/issue", p.checkAuth(p.attachUserContext(p.getIssueByNumber), ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/pr", p.checkAuth(p.attachUserContext(p.getPrByNumber), ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/stats", p.checkAuth(p.attachContext(p.getStats), ResponseTypeJSON)).Methods(http.MethodGet)
+
+	apiRouter.HandleFunc("/subscriptions", p.checkAuth(p.attachContext(p.getSubscriptions), ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/subscriptions", p.checkAuth(p.attachUserContext(p.createSubscription), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/subscriptions", p.checkAuth(p.attachUserContext(p.updateSubscription), ResponseTypeJSON)).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/subscriptions", p.checkAuth(p.attachContext(p.deleteSubscription), ResponseTypeJSON)).Methods(http.MethodDelete)
+
+	actionsRouter := apiRouter.PathPrefix("/actions").Subrouter()
+	actionsRouter.HandleFunc("/pr", p.checkAuth(p.attachContext(p.handlePullRequestAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	actionsRouter.HandleFunc("/issue", p.checkAuth(p.attachContext(p.handleIssueAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	actionsRouter.HandleFunc("/pr/review", p.checkAuth(p.attachContext(p.handlePullRequestReviewDialog), ResponseTypeJSON)).Methods(http.MethodPost)
 
 	apiRouter.HandleFunc("/config", checkPluginRequest(p.getConfig)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/token", checkPluginRequest(p.getToken)).Methods(http.MethodGet)
@@ -372,6 +383,8 @@ func (p *Plugin) completeConnectUserToGitHub(c *Context, w http.ResponseWriter,
 		p.API.LogWarn("Failed to store GitHub user info mapping", "error", err.Error())
 	}
 
+	p.invalidatePermissions(state.UserID)
+
 	commandHelp, err := renderTemplate("helpText", p.getConfiguration())
 	if err != nil {
 		p.API.LogWarn("Failed to render help template", "error", err.Error())
@@ -1043,15 +1056,61 @@ func (p *Plugin) getLabels(c *UserContext, w http.ResponseWriter, r *http.Reques
 	}
 
 	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
+	allLabels, err := listLabels(c.Ctx, githubClient, owner, repo)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to list labels")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch labels", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allLabels)
+}
+
+func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Request) {
+	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
+	allAssignees, err := listAssignees(c.Ctx, githubClient, owner, repo)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to list assignees")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch assignees", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allAssignees)
+}
+
+func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Request) {
+	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
+	allMilestones, err := listMilestones(c.Ctx, githubClient, owner, repo)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to list milestones")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch milestones", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allMilestones)
+}
+
+// listLabels returns all labels of the given repository.
+func listLabels(ctx context.Context, githubClient *github.Client, owner, repo string) ([]*github.Label, error) {
 	var allLabels []*github.Label
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
-		labels, resp, err := githubClient.Issues.ListLabels(c.Ctx, owner, repo, &opt)
+		labels, resp, err := githubClient.Issues.ListLabels(ctx, owner, repo, &opt)
 		if err != nil {
-			c.Logger.WithError(err).Warnf("Failed to list labels")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch labels", StatusCode: http.StatusInternalServerError})
-			return
+			return nil, err
 		}
 		allLabels = append(allLabels, labels...)
 		if resp.NextPage == 0 {
@@ -1060,26 +1119,18 @@ func (p *Plugin) getLabels(c *UserContext, w http.ResponseWriter, r *http.Reques
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allLabels)
+	return allLabels, nil
 }
 
-func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Request) {
-	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
-	if err != nil {
-		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
-		return
-	}
-
-	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
+// listAssignees returns all users issues of the given repository can be assigned to.
+func listAssignees(ctx context.Context, githubClient *github.Client, owner, repo string) ([]*github.User, error) {
 	var allAssignees []*github.User
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
-		assignees, resp, err := githubClient.Issues.ListAssignees(c.Ctx, owner, repo, &opt)
+		assignees, resp, err := githubClient.Issues.ListAssignees(ctx, owner, repo, &opt)
 		if err != nil {
-			c.Logger.WithError(err).Warnf("Failed to list assignees")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch assignees", StatusCode: http.StatusInternalServerError})
-			return
+			return nil, err
 		}
 		allAssignees = append(allAssignees, assignees...)
 		if resp.NextPage == 0 {
@@ -1088,26 +1139,18 @@ func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Req
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allAssignees)
+	return allAssignees, nil
 }
 
-func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Request) {
-	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
-	if err != nil {
-		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
-		return
-	}
-
-	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
+// listMilestones returns all open milestones of the given repository.
+func listMilestones(ctx context.Context, githubClient *github.Client, owner, repo string) ([]*github.Milestone, error) {
 	var allMilestones []*github.Milestone
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
-		milestones, resp, err := githubClient.Issues.ListMilestones(c.Ctx, owner, repo, &github.MilestoneListOptions{ListOptions: opt})
+		milestones, resp, err := githubClient.Issues.ListMilestones(ctx, owner, repo, &github.MilestoneListOptions{ListOptions: opt})
 		if err != nil {
-			c.Logger.WithError(err).Warnf("Failed to list milestones")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch milestones", StatusCode: http.StatusInternalServerError})
-			return
+			return nil, err
 		}
 		allMilestones = append(allMilestones, milestones...)
 		if resp.NextPage == 0 {
@@ -1116,7 +1159,7 @@ func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Re
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allMilestones)
+	return allMilestones, nil
 }
 
 func (p *Plugin) getRepositories(c *UserContext, w http.ResponseWriter, r *http.Request) {
@@ -1157,11 +1200,20 @@ func (p *Plugin) getRepositories(c *UserContext, w http.ResponseWriter, r *http.
 		}
 	}
 
+	// The default repository of the channel the repositories are listed for is preselected by the webapp
+	defaultRepo := ""
+	if channelID := r.URL.Query().Get("channel_id"); channelID != "" && p.API.HasPermissionToChannel(c.UserID, channelID, model.PermissionReadChannel) {
+		if owner, repo := p.getChannelDefaultRepo(channelID); repo != "" {
+			defaultRepo = fullNameFromOwnerAndRepo(owner, repo)
+		}
+	}
+
 	// Only send down fields to client that are needed
 	type RepositoryResponse struct {
 		Name        string          `json:"name,omitempty"`
 		FullName    string          `json:"full_name,omitempty"`
 		Permissions map[string]bool `json:"permissions,omitempty"`
+		Default     bool            `json:"default,omitempty"`
 	}
 
 	resp := make([]RepositoryResponse, len(allRepos))
@@ -1169,23 +1221,25 @@ func (p *Plugin) getRepositories(c *UserContext, w http.ResponseWriter, r *http.
 		resp[i].Name = r.GetName()
 		resp[i].FullName = r.GetFullName()
 		resp[i].Permissions = r.GetPermissions()
+		resp[i].Default = defaultRepo != "" && strings.EqualFold(r.GetFullName(), defaultRepo)
 	}
 
 	p.writeJSON(w, resp)
 }
 
-func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Request) {
-	type IssueRequest struct {
-		Title     string   `json:"title"`
-		Body      string   `json:"body"`
-		Repo      string   `json:"repo"`
-		PostID    string   `json:"post_id"`
-		ChannelID string   `json:"channel_id"`
-		Labels    []string `json:"labels"`
-		Assignees []string `json:"assignees"`
-		Milestone int      `json:"milestone"`
-	}
+// IssueRequest describes an issue to create, either from the webapp or from /github issue create.
+type IssueRequest struct {
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	Repo      string   `json:"repo"`
+	PostID    string   `json:"post_id"`
+	ChannelID string   `json:"channel_id"`
+	Labels    []string `json:"labels"`
+	Assignees []string `json:"assignees"`
+	Milestone int      `json:"milestone"`
+}
 
+func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Request) {
 	// get data for the issue from the request body and fill IssueRequest object
 	issue := &IssueRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
@@ -1194,19 +1248,28 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if issue.Title == "" {
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid issue title.", StatusCode: http.StatusBadRequest})
+	result, apiErr := p.createGitHubIssue(c.Ctx, c.UserID, c.GHInfo, issue)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
 		return
 	}
 
+	p.writeJSON(w, result)
+}
+
+// createGitHubIssue creates an issue on behalf of the user and posts the confirmation: a reply to the post
+// the issue was created from, or an ephemeral post in the channel.
+func (p *Plugin) createGitHubIssue(ctx context.Context, userID string, userInfo *GitHubUserInfo, issue *IssueRequest) (*github.Issue, *APIErrorResponse) {
+	if issue.Title == "" {
+		return nil, &APIErrorResponse{ID: "", Message: "Please provide a valid issue title.", StatusCode: http.StatusBadRequest}
+	}
+
 	if issue.Repo == "" {
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid repo name.", StatusCode: http.StatusBadRequest})
-		return
+		return nil, &APIErrorResponse{ID: "", Message: "Please provide a valid repo name.", StatusCode: http.StatusBadRequest}
 	}
 
 	if issue.PostID == "" && issue.ChannelID == "" {
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide either a postID or a channelID", StatusCode: http.StatusBadRequest})
-		return
+		return nil, &APIErrorResponse{ID: "", Message: "Please provide either a postID or a channelID", StatusCode: http.StatusBadRequest}
 	}
 
 	mmMessage := ""
@@ -1216,18 +1279,15 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		var appErr *model.AppError
 		post, appErr = p.API.GetPost(issue.PostID)
 		if appErr != nil {
-			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load post " + issue.PostID, StatusCode: http.StatusInternalServerError})
-			return
+			return nil, &APIErrorResponse{ID: "", Message: "failed to load post " + issue.PostID, StatusCode: http.StatusInternalServerError}
 		}
 		if post == nil {
-			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load post " + issue.PostID + ": not found", StatusCode: http.StatusNotFound})
-			return
+			return nil, &APIErrorResponse{ID: "", Message: "failed to load post " + issue.PostID + ": not found", StatusCode: http.StatusNotFound}
 		}
 
 		username, err := p.getUsername(post.UserId)
 		if err != nil {
-			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to get username", StatusCode: http.StatusInternalServerError})
-			return
+			return nil, &APIErrorResponse{ID: "", Message: "failed to get username", StatusCode: http.StatusInternalServerError}
 		}
 
 		permalink = p.getPermaLink(issue.PostID)
@@ -1253,35 +1313,36 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 	}
 	*ghIssue.Body = ghIssue.GetBody() + mmMessage
 
-	currentUser, appErr := p.API.GetUser(c.UserID)
+	currentUser, appErr := p.API.GetUser(userID)
 	if appErr != nil {
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load current user", StatusCode: http.StatusInternalServerError})
-		return
+		return nil, &APIErrorResponse{ID: "", Message: "failed to load current user", StatusCode: http.StatusInternalServerError}
 	}
 
-	splittedRepo := strings.Split(issue.Repo, "/")
-	owner := splittedRepo[0]
-	repoName := splittedRepo[1]
+	owner, repoName := parseOwnerAndRepo(issue.Repo, p.getBaseURL())
+	if repoName == "" {
+		return nil, &APIErrorResponse{ID: "", Message: "Please provide a valid repo name.", StatusCode: http.StatusBadRequest}
+	}
 
-	githubClient := p.githubConnectUser(c.Context.Ctx, c.GHInfo)
-	result, resp, err := githubClient.Issues.Create(c.Ctx, owner, repoName, ghIssue)
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	result, resp, err := githubClient.Issues.Create(ctx, owner, repoName, ghIssue)
 	if err != nil {
 		if resp != nil && resp.Response.StatusCode == http.StatusGone {
-			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Issues are disabled on this repository.", StatusCode: http.StatusMethodNotAllowed})
-			return
+			return nil, &APIErrorResponse{ID: "", Message: "Issues are disabled on this repository.", StatusCode: http.StatusMethodNotAllowed}
 		}
 
-		c.Logger.WithError(err).Warnf("Failed to create issue")
-		p.writeAPIError(w,
-			&APIErrorResponse{
-				ID: "",
-				Message: "failed to create issue: " + getFailReason(resp.StatusCode,
-					issue.Repo,
-					currentUser.Username,
-				),
-				StatusCode: resp.StatusCode,
-			})
-		return
+		p.API.LogWarn("Failed to create issue", "repo", issue.Repo, "error", err.Error())
+		statusCode := http.StatusInternalServerError
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		return nil, &APIErrorResponse{
+			ID: "",
+			Message: "failed to create issue: " + getFailReason(statusCode,
+				issue.Repo,
+				currentUser.Username,
+			),
+			StatusCode: statusCode,
+		}
 	}
 
 	rootID := issue.PostID
@@ -1299,21 +1360,594 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		Message:   message,
 		ChannelId: channelID,
 		RootId:    rootID,
-		UserId:    c.UserID,
+		UserId:    userID,
 	}
 
 	if post != nil {
 		_, appErr = p.API.CreatePost(reply)
 	} else {
-		p.API.SendEphemeralPost(c.UserID, reply)
+		p.API.SendEphemeralPost(userID, reply)
 	}
 	if appErr != nil {
 		p.API.LogWarn("failed to create notification post", "error", appErr.Error())
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to create notification post, postID: " + issue.PostID + ", channelID: " + channelID, StatusCode: http.StatusInternalServerError})
+		return nil, &APIErrorResponse{ID: "", Message: "failed to create notification post, postID: " + issue.PostID + ", channelID: " + channelID, StatusCode: http.StatusInternalServerError}
+	}
+
+	return result, nil
+}
+
+// postActionRequest is a post action integration request made by a connected user
+// for an issue or pull request.
+type postActionRequest struct {
+	*model.PostActionIntegrationRequest
+	Info   *GitHubUserInfo
+	Post   *model.Post
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// readPostAction decodes the post action integration request of r. If the request is invalid
+// or the user isn't connected to GitHub, an ephemeral error is written to w and false is returned.
+func (p *Plugin) readPostAction(c *Context, w http.ResponseWriter, r *http.Request) (*postActionRequest, bool) {
+	request := &model.PostActionIntegrationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Logger.WithError(err).Warnf("Error decoding PostActionIntegrationRequest JSON body")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return nil, false
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+
+	owner, repo, number, err := parsePostActionContext(request.Context)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Invalid post action context")
+		response.EphemeralText = "Invalid action."
+		p.writeJSON(w, response)
+		return nil, false
+	}
+
+	info, apiErr := p.getGitHubUserInfo(c.UserID)
+	if apiErr != nil {
+		response.EphemeralText = "Unknown error."
+		if apiErr.ID == apiErrorIDNotConnected {
+			response.EphemeralText = "You must connect your account to GitHub first. Either click on the GitHub logo in the bottom left of the screen or enter `/github connect`."
+		}
+		p.writeJSON(w, response)
+		return nil, false
+	}
+
+	post, appErr := p.API.GetPost(request.PostId)
+	if appErr != nil {
+		c.Logger.WithError(appErr).Warnf("Failed to get post for post action")
+		response.EphemeralText = "Failed to load the post."
+		p.writeJSON(w, response)
+		return nil, false
+	}
+
+	if !p.canUpdateActionPost(c.UserID, post, owner, repo, number) {
+		response.EphemeralText = "Invalid action."
+		p.writeJSON(w, response)
+		return nil, false
+	}
+
+	return &postActionRequest{
+		PostActionIntegrationRequest: request,
+		Info:                         info,
+		Post:                         post,
+		Owner:                        owner,
+		Repo:                         repo,
+		Number:                       number,
+	}, true
+}
+
+func (p *Plugin) handlePullRequestAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	request, ok := p.readPostAction(c, w, r)
+	if !ok {
 		return
 	}
 
-	p.writeJSON(w, result)
+	response := &model.PostActionIntegrationResponse{}
+	info, post, owner, repo, number := request.Info, request.Post, request.Owner, request.Repo, request.Number
+
+	githubClient := p.githubConnectUser(c.Ctx, info)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+	prLink := fmt.Sprintf("[%s#%d](%s%s/pull/%d)", repoName, number, p.getBaseURL(), repoName, number)
+
+	var resp *github.Response
+	var err error
+	var done string
+	action, _ := request.Context[postActionContextAction].(string)
+	switch action {
+	case postActionAssignMe:
+		_, resp, err = githubClient.Issues.AddAssignees(c.Ctx, owner, repo, number, []string{info.GitHubUsername})
+		done = "You have been assigned to " + prLink + "."
+	case postActionApprove:
+		review := &github.PullRequestReviewRequest{Event: github.String("APPROVE")}
+		_, resp, err = githubClient.PullRequests.CreateReview(c.Ctx, owner, repo, number, review)
+		done = "You approved " + prLink + "."
+	case postActionRequestChanges:
+		p.openRequestChangesDialog(c, request.PostActionIntegrationRequest, response)
+		p.writeJSON(w, response)
+		return
+	case postActionMerge:
+		method, _ := request.Context[postActionSelected].(string)
+		_, resp, err = githubClient.PullRequests.Merge(c.Ctx, owner, repo, number, "", &github.PullRequestOptions{MergeMethod: method})
+		done = "You merged " + prLink + "."
+	case postActionClose:
+		_, resp, err = githubClient.PullRequests.Edit(c.Ctx, owner, repo, number, &github.PullRequest{State: github.String("closed")})
+		done = "You closed " + prLink + "."
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown pull request action %q.", action)
+		p.writeJSON(w, response)
+		return
+	}
+
+	if err != nil {
+		c.Logger.WithError(err).Debugf("Failed to run pull request action %s", action)
+		response.EphemeralText = "Failed to update the pull request: " + getActionFailReason(resp, err, "update pull requests", repoName, info.GitHubUsername)
+		p.writeJSON(w, response)
+		return
+	}
+
+	updated, err := p.refreshPullRequestPost(c.Ctx, githubClient, post, owner, repo, number)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to refresh pull request post")
+	}
+
+	response.Update = updated
+	response.EphemeralText = done
+	p.writeJSON(w, response)
+}
+
+func (p *Plugin) handleIssueAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	request, ok := p.readPostAction(c, w, r)
+	if !ok {
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	info, owner, repo, number := request.Info, request.Owner, request.Repo, request.Number
+
+	githubClient := p.githubConnectUser(c.Ctx, info)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+	issueLink := fmt.Sprintf("[%s#%d](%s%s/issues/%d)", repoName, number, p.getBaseURL(), repoName, number)
+
+	var resp *github.Response
+	var err error
+	var done string
+	action, _ := request.Context[postActionContextAction].(string)
+	selected, _ := request.Context[postActionSelected].(string)
+	switch action {
+	case postActionAddLabel:
+		_, resp, err = githubClient.Issues.AddLabelsToIssue(c.Ctx, owner, repo, number, []string{selected})
+		done = fmt.Sprintf("You added the label `%s` to %s.", selected, issueLink)
+	case postActionRemoveLabel:
+		resp, err = githubClient.Issues.RemoveLabelForIssue(c.Ctx, owner, repo, number, selected)
+		done = fmt.Sprintf("You removed the label `%s` from %s.", selected, issueLink)
+	case postActionSetMilestone:
+		milestone, convErr := strconv.Atoi(selected)
+		if convErr != nil {
+			response.EphemeralText = "Invalid milestone."
+			p.writeJSON(w, response)
+			return
+		}
+		_, resp, err = githubClient.Issues.Edit(c.Ctx, owner, repo, number, &github.IssueRequest{Milestone: &milestone})
+		done = "You set the milestone of " + issueLink + "."
+	case postActionAssign:
+		_, resp, err = githubClient.Issues.AddAssignees(c.Ctx, owner, repo, number, []string{selected})
+		done = fmt.Sprintf("You assigned %s to %s.", selected, issueLink)
+	case postActionClose:
+		_, resp, err = githubClient.Issues.Edit(c.Ctx, owner, repo, number, &github.IssueRequest{State: github.String("closed")})
+		done = "You closed " + issueLink + "."
+	case postActionReopen:
+		_, resp, err = githubClient.Issues.Edit(c.Ctx, owner, repo, number, &github.IssueRequest{State: github.String("open")})
+		done = "You reopened " + issueLink + "."
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown issue action %q.", action)
+		p.writeJSON(w, response)
+		return
+	}
+
+	if err != nil {
+		c.Logger.WithError(err).Debugf("Failed to run issue action %s", action)
+		response.EphemeralText = "Failed to update the issue: " + getActionFailReason(resp, err, "update issues", repoName, info.GitHubUsername)
+		p.writeJSON(w, response)
+		return
+	}
+
+	updated, err := p.refreshIssuePost(c.Ctx, githubClient, c.UserID, request.Post, owner, repo, number)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to refresh issue post")
+	}
+
+	response.Update = updated
+	response.EphemeralText = done
+	p.writeJSON(w, response)
+}
+
+// openRequestChangesDialog asks the user for the review comment GitHub requires when requesting changes.
+func (p *Plugin) openRequestChangesDialog(c *Context, request *model.PostActionIntegrationRequest, response *model.PostActionIntegrationResponse) {
+	state, err := json.Marshal(map[string]interface{}{
+		postActionContextRepo:   request.Context[postActionContextRepo],
+		postActionContextNumber: request.Context[postActionContextNumber],
+		postActionContextPostID: request.PostId,
+	})
+	if err != nil {
+		response.EphemeralText = "Failed to open the review dialog."
+		return
+	}
+
+	dialog := model.OpenDialogRequest{
+		TriggerId: request.TriggerId,
+		URL:       getPostActionURL("/pr/review"),
+		Dialog: model.Dialog{
+			Title:       "Request changes",
+			SubmitLabel: "Request changes",
+			State:       string(state),
+			Elements: []model.DialogElement{{
+				DisplayName: "Comment",
+				Name:        "body",
+				Type:        "textarea",
+				HelpText:    "Describe the changes you are requesting.",
+			}},
+		},
+	}
+
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		c.Logger.WithError(appErr).Warnf("Failed to open request changes dialog")
+		response.EphemeralText = "Failed to open the review dialog."
+	}
+}
+
+func (p *Plugin) handlePullRequestReviewDialog(c *Context, w http.ResponseWriter, r *http.Request) {
+	request := &model.SubmitDialogRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Logger.WithError(err).Warnf("Error decoding SubmitDialogRequest JSON body")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if request.Cancelled {
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil {
+		response.Error = "Invalid dialog state."
+		p.writeJSON(w, response)
+		return
+	}
+
+	owner, repo, number, err := parsePostActionContext(state)
+	if err != nil {
+		response.Error = "Invalid dialog state."
+		p.writeJSON(w, response)
+		return
+	}
+
+	body, _ := request.Submission["body"].(string)
+	if strings.TrimSpace(body) == "" {
+		response.Errors = map[string]string{"body": "Please describe the changes you are requesting."}
+		p.writeJSON(w, response)
+		return
+	}
+
+	postID, _ := state[postActionContextPostID].(string)
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil || !p.canUpdateActionPost(c.UserID, post, owner, repo, number) {
+		response.Error = "Invalid dialog state."
+		p.writeJSON(w, response)
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(c.UserID)
+	if apiErr != nil {
+		response.Error = apiErr.Message
+		p.writeJSON(w, response)
+		return
+	}
+
+	githubClient := p.githubConnectUser(c.Ctx, info)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+	review := &github.PullRequestReviewRequest{
+		Event: github.String("REQUEST_CHANGES"),
+		Body:  github.String(body),
+	}
+
+	if _, resp, err := githubClient.PullRequests.CreateReview(c.Ctx, owner, repo, number, review); err != nil {
+		c.Logger.WithError(err).Debugf("Failed to request changes")
+		response.Error = "Failed to request changes: " + getActionFailReason(resp, err, "review pull requests", repoName, info.GitHubUsername)
+		p.writeJSON(w, response)
+		return
+	}
+
+	if updated, err := p.refreshPullRequestPost(c.Ctx, githubClient, post, owner, repo, number); err == nil {
+		if _, appErr := p.API.UpdatePost(updated); appErr != nil {
+			c.Logger.WithError(appErr).Warnf("Failed to update pull request post")
+		}
+	}
+
+	p.API.SendEphemeralPost(c.UserID, &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: request.ChannelId,
+		Message:   fmt.Sprintf("You requested changes on [%s#%d](%s%s/pull/%d).", repoName, number, p.getBaseURL(), repoName, number),
+	})
+
+	p.writeJSON(w, response)
+}
+
+func (p *Plugin) getStats(c *Context, w http.ResponseWriter, r *http.Request) {
+	isSysAdmin, err := p.isAuthorizedSysAdmin(c.UserID)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to check if user is a system admin")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to check permissions.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+	if !isSysAdmin {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can view the plugin statistics.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	stats := struct {
+		PermissionCache PermissionCacheStats `json:"permission_cache"`
+	}{
+		PermissionCache: p.getPermissionCacheStats(),
+	}
+
+	p.writeJSON(w, stats)
+}
+
+// SubscriptionResponse is a channel subscription as returned by the subscriptions API.
+// Features and flags use the same syntax as /github subscriptions add.
+type SubscriptionResponse struct {
+	ChannelID  string `json:"channel_id"`
+	Repository string `json:"repository"`
+	Features   string `json:"features"`
+	Flags      string `json:"flags"`
+	CreatorID  string `json:"creator_id"`
+}
+
+// SubscriptionRequest creates or updates the subscription of a channel to a repository or organization.
+type SubscriptionRequest struct {
+	ChannelID  string `json:"channel_id"`
+	Repository string `json:"repository"`
+	Features   string `json:"features"`
+	Flags      string `json:"flags"`
+}
+
+func newSubscriptionResponse(sub *Subscription) *SubscriptionResponse {
+	return &SubscriptionResponse{
+		ChannelID:  sub.ChannelID,
+		Repository: strings.Trim(sub.Repository, "/"),
+		Features:   sub.Features,
+		Flags:      sub.Flags.String(),
+		CreatorID:  sub.CreatorID,
+	}
+}
+
+// checkSubscriptionChannelAccess checks that userID is a member of channelID and, if manage is set,
+// allowed to manage the channel.
+func (p *Plugin) checkSubscriptionChannelAccess(userID, channelID string, manage bool) *APIErrorResponse {
+	if channelID == "" {
+		return &APIErrorResponse{ID: "", Message: "Please provide a channel id.", StatusCode: http.StatusBadRequest}
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return &APIErrorResponse{ID: "", Message: "Channel not found.", StatusCode: http.StatusNotFound}
+	}
+
+	if _, appErr = p.API.GetChannelMember(channelID, userID); appErr != nil {
+		return &APIErrorResponse{ID: "", Message: "You must be a member of the channel.", StatusCode: http.StatusForbidden}
+	}
+
+	if !manage {
+		return nil
+	}
+
+	var permission *model.Permission
+	switch channel.Type {
+	case model.ChannelTypeOpen:
+		permission = model.PermissionManagePublicChannelProperties
+	case model.ChannelTypePrivate:
+		permission = model.PermissionManagePrivateChannelProperties
+	default:
+		return nil
+	}
+
+	if !p.API.HasPermissionToChannel(userID, channelID, permission) {
+		return &APIErrorResponse{ID: "", Message: "You are not allowed to manage the subscriptions of this channel.", StatusCode: http.StatusForbidden}
+	}
+
+	return nil
+}
+
+// getSubscriptionKey returns the key subscriptions of the given owner/repo or organization are stored under,
+// together with the lowercase owner and repository.
+func (p *Plugin) getSubscriptionKey(repository string) (string, string, string) {
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	owner = strings.ToLower(owner)
+	repo = strings.ToLower(repo)
+
+	return fullNameFromOwnerAndRepo(owner, repo), owner, repo
+}
+
+// findChannelSubscription returns the subscription of channelID stored under key, or nil if there is none.
+func (p *Plugin) findChannelSubscription(channelID, key string) (*Subscription, error) {
+	repoSubs, err := p.getRepoSubscriptions(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range repoSubs {
+		if sub.ChannelID == channelID {
+			return sub, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (p *Plugin) getSubscriptions(c *Context, w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channel_id")
+	repository := r.URL.Query().Get("repository")
+
+	var subs []*Subscription
+	switch {
+	case channelID != "":
+		if apiErr := p.checkSubscriptionChannelAccess(c.UserID, channelID, false); apiErr != nil {
+			p.writeAPIError(w, apiErr)
+			return
+		}
+
+		var err error
+		subs, err = p.GetSubscriptionsByChannel(channelID)
+		if err != nil {
+			c.Logger.WithError(err).Warnf("Failed to get subscriptions of channel")
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscriptions.", StatusCode: http.StatusInternalServerError})
+			return
+		}
+	case repository != "":
+		key, _, _ := p.getSubscriptionKey(repository)
+		repoSubs, err := p.getRepoSubscriptions(key)
+		if err != nil {
+			c.Logger.WithError(err).Warnf("Failed to get subscriptions of repository")
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscriptions.", StatusCode: http.StatusInternalServerError})
+			return
+		}
+
+		// Only subscriptions of channels the user is a member of are listed.
+		for _, sub := range repoSubs {
+			if p.checkSubscriptionChannelAccess(c.UserID, sub.ChannelID, false) == nil {
+				subs = append(subs, sub)
+			}
+		}
+	default:
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a channel id or repository.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	response := make([]*SubscriptionResponse, 0, len(subs))
+	for _, sub := range subs {
+		response = append(response, newSubscriptionResponse(sub))
+	}
+
+	p.writeJSON(w, response)
+}
+
+func (p *Plugin) createSubscription(c *UserContext, w http.ResponseWriter, r *http.Request) {
+	p.storeSubscription(c, w, r, false)
+}
+
+func (p *Plugin) updateSubscription(c *UserContext, w http.ResponseWriter, r *http.Request) {
+	p.storeSubscription(c, w, r, true)
+}
+
+// storeSubscription creates or, if update is set, updates a subscription with the same validation as /github subscriptions add.
+func (p *Plugin) storeSubscription(c *UserContext, w http.ResponseWriter, r *http.Request, update bool) {
+	req := &SubscriptionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		c.Logger.WithError(err).Warnf("Error decoding SubscriptionRequest JSON body")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if req.Repository == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a repository.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if apiErr := p.checkSubscriptionChannelAccess(c.UserID, req.ChannelID, true); apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	var parameters []string
+	if req.Features != "" {
+		parameters = append(parameters, req.Features)
+	}
+	parameters = append(parameters, splitSubscriptionFlags(req.Flags)...)
+	features, flags, err := parseSubscriptionOptions(parameters)
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	key, owner, repo := p.getSubscriptionKey(req.Repository)
+	existing, err := p.findChannelSubscription(req.ChannelID, key)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to get subscriptions of repository")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscriptions.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+	if update && existing == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Subscription not found.", StatusCode: http.StatusNotFound})
+		return
+	}
+	if !update && existing != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "The channel is already subscribed to this repository.", StatusCode: http.StatusConflict})
+		return
+	}
+
+	if repo == "" {
+		flags.qualifyExcludeRepos(owner)
+	}
+
+	githubClient := p.githubConnectUser(c.Ctx, c.GHInfo)
+	if err := p.Subscribe(c.Ctx, githubClient, c.UserID, owner, repo, req.ChannelID, features, flags); err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	sub, err := p.findChannelSubscription(req.ChannelID, key)
+	if err != nil || sub == nil {
+		c.Logger.WithError(err).Warnf("Failed to get stored subscription")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscription.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if !update {
+		w.WriteHeader(http.StatusCreated)
+	}
+	p.writeJSON(w, newSubscriptionResponse(sub))
+}
+
+func (p *Plugin) deleteSubscription(c *Context, w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channel_id")
+	repository := r.URL.Query().Get("repository")
+
+	if repository == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a repository.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if apiErr := p.checkSubscriptionChannelAccess(c.UserID, channelID, true); apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	key, _, _ := p.getSubscriptionKey(repository)
+	existing, err := p.findChannelSubscription(channelID, key)
+	if err != nil {
+		c.Logger.WithError(err).Warnf("Failed to get subscriptions of repository")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscriptions.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+	if existing == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Subscription not found.", StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if err := p.Unsubscribe(channelID, repository, c.UserID); err != nil {
+		c.Logger.WithError(err).Warnf("Failed to unsubscribe")
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to delete subscription.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (p *Plugin) getConfig(w http.ResponseWriter, r *http.Request) {
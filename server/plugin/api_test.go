@@ -1,14 +1,19 @@
 package plugin
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -243,3 +248,120 @@ func TestGetConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscriptionsAPI(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "member", Repository: "mattermost/mattermost-server", Features: "pulls"},
+		{ChannelID: "other", Repository: "mattermost/mattermost-server", Features: "issues"},
+	})
+	p.setConfiguration(&Configuration{
+		GitHubOrg:               "mockOrg",
+		GitHubOAuthClientID:     "mockID",
+		GitHubOAuthClientSecret: "mockSecret",
+		EncryptionKey:           "mockKey",
+	})
+	p.initializeAPI()
+
+	api := p.API.(*plugintest.API)
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("GetChannel", "member").Return(&model.Channel{Id: "member", Type: model.ChannelTypeOpen}, nil)
+	api.On("GetChannel", "other").Return(&model.Channel{Id: "other", Type: model.ChannelTypeOpen}, nil)
+	api.On("GetChannelMember", "member", "user").Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", "other", "user").Return(nil, &model.AppError{Message: "not found"})
+	api.On("HasPermissionToChannel", "user", "member", model.PermissionManagePublicChannelProperties).Return(false)
+
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Mattermost-User-ID", "user")
+		rr := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, rr, req)
+		return rr
+	}
+
+	t.Run("list by channel", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/api/v1/subscriptions?channel_id=member")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var subs []*SubscriptionResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+		assert.Equal(t, []*SubscriptionResponse{{ChannelID: "member", Repository: "mattermost/mattermost-server", Features: "pulls"}}, subs)
+	})
+
+	t.Run("list by channel requires membership", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/api/v1/subscriptions?channel_id=other")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("list by repository only includes channels of the user", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/api/v1/subscriptions?repository=Mattermost/mattermost-server")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var subs []*SubscriptionResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+		require.Len(t, subs, 1)
+		assert.Equal(t, "member", subs[0].ChannelID)
+	})
+
+	t.Run("delete requires permission to manage the channel", func(t *testing.T) {
+		rr := serve(http.MethodDelete, "/api/v1/subscriptions?channel_id=member&repository=mattermost/mattermost-server")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		subs, err := p.GetSubscriptionsByChannel("member")
+		require.NoError(t, err)
+		assert.Len(t, subs, 1)
+	})
+}
+
+func TestSubscriptionsAPIRoundTrip(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name": "mattermost/mattermost-server"}`)
+	})
+
+	sub := &Subscription{
+		ChannelID:  "member",
+		CreatorID:  "user",
+		Repository: "mattermost/mattermost-server",
+		Features:   "pulls,issues",
+		Flags:      SubscriptionFlags{ExcludeOrgMembers: true, RenderStyle: renderStyleAttachment},
+	}
+	p := pluginWithMockedSubs([]*Subscription{sub})
+	userInfo := serveTestGitHub(t, p, &Configuration{GitHubOrg: "mattermost", GitHubOAuthClientID: "mockID", GitHubOAuthClientSecret: "mockSecret"}, mux)
+	require.NoError(t, p.storeGitHubUserInfo(userInfo))
+	p.initializeAPI()
+
+	api := p.API.(*plugintest.API)
+	api.On("GetChannel", "member").Return(&model.Channel{Id: "member", Type: model.ChannelTypeOpen}, nil)
+	api.On("GetChannelMember", "member", "user").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionToChannel", "user", "member", model.PermissionManagePublicChannelProperties).Return(true)
+
+	serve := func(method, url string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, body)
+		req.Header.Set("Mattermost-User-ID", "user")
+		rr := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/api/v1/subscriptions?channel_id=member", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var subs []*SubscriptionResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+	require.Len(t, subs, 1)
+	assert.Equal(t, "--exclude-org-member --render-style attachment", subs[0].Flags)
+
+	// A subscription that was read can be sent back unchanged.
+	body, err := json.Marshal(&SubscriptionRequest{ChannelID: subs[0].ChannelID, Repository: subs[0].Repository, Features: subs[0].Features, Flags: subs[0].Flags})
+	require.NoError(t, err)
+	rr = serve(http.MethodPut, "/api/v1/subscriptions", bytes.NewReader(body))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var updated SubscriptionResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, *subs[0], updated)
+
+	stored, err := p.GetSubscriptionsByChannel("member")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, sub.Flags, stored[0].Flags)
+}
@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const (
+	renderStyleMarkdown   = "markdown"
+	renderStyleAttachment = "attachment"
+
+	attachmentColorOpen    = "#2cbe4e"
+	attachmentColorMerged  = "#6f42c1"
+	attachmentColorClosed  = "#cb2431"
+	attachmentColorNeutral = "#586069"
+)
+
+// isValidRenderStyle reports whether style is a known way of rendering events.
+func isValidRenderStyle(style string) bool {
+	return style == renderStyleMarkdown || style == renderStyleAttachment
+}
+
+// getRenderStyle returns how events should be rendered for the given subscription.
+// The subscription's own choice takes precedence over the plugin configuration.
+func (p *Plugin) getRenderStyle(sub *Subscription) string {
+	if sub != nil && isValidRenderStyle(sub.Flags.RenderStyle) {
+		return sub.Flags.RenderStyle
+	}
+
+	if style := p.getConfiguration().NotificationRenderStyle; isValidRenderStyle(style) {
+		return style
+	}
+
+	return renderStyleMarkdown
+}
+
+// createSubscriptionPost creates a copy of post in the channel of the given subscription.
+// If the subscription renders events as message attachments and an attachment is given,
+// the attachment replaces the markdown message. Markdown posts still carry the actions
+// of the attachment, if any.
+func (p *Plugin) createSubscriptionPost(sub *Subscription, post *model.Post, attachment *model.SlackAttachment) *model.Post {
+	post = post.Clone()
+	post.ChannelId = sub.ChannelID
+
+	switch {
+	case attachment == nil:
+	case p.getRenderStyle(sub) == renderStyleAttachment:
+		post.Message = ""
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+	case len(attachment.Actions) > 0:
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{{Actions: attachment.Actions}})
+	}
+
+	created, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		p.API.LogWarn("Error webhook post", "post", post, "error", appErr.Error())
+		return nil
+	}
+
+	return created
+}
+
+// newEventAttachment returns an attachment showing text on behalf of sender.
+func newEventAttachment(sender *github.User, text string) *model.SlackAttachment {
+	return &model.SlackAttachment{
+		Fallback:   text,
+		Color:      attachmentColorNeutral,
+		AuthorName: sender.GetLogin(),
+		AuthorIcon: sender.GetAvatarURL(),
+		AuthorLink: sender.GetHTMLURL(),
+		Text:       text,
+	}
+}
+
+// newPullRequestAttachment returns an attachment describing a pull request, colored by its state.
+func newPullRequestAttachment(repo *github.Repository, pr *github.PullRequest, sender *github.User, text string) *model.SlackAttachment {
+	attachment := newEventAttachment(sender, text)
+	attachment.Title = fmt.Sprintf("%s#%d %s", repo.GetFullName(), pr.GetNumber(), pr.GetTitle())
+	attachment.TitleLink = pr.GetHTMLURL()
+
+	switch {
+	case pr.GetMerged():
+		attachment.Color = attachmentColorMerged
+	case pr.GetState() == "closed":
+		attachment.Color = attachmentColorClosed
+	default:
+		attachment.Color = attachmentColorOpen
+	}
+
+	attachment.Fields = attachmentFields(pr.Labels, pr.Assignees, pr.RequestedReviewers)
+
+	return attachment
+}
+
+// newIssueAttachment returns an attachment describing an issue, colored by its state.
+func newIssueAttachment(repo *github.Repository, issue *github.Issue, sender *github.User, text string) *model.SlackAttachment {
+	attachment := newEventAttachment(sender, text)
+	attachment.Title = fmt.Sprintf("%s#%d %s", repo.GetFullName(), issue.GetNumber(), issue.GetTitle())
+	attachment.TitleLink = issue.GetHTMLURL()
+
+	if issue.GetState() == "closed" {
+		attachment.Color = attachmentColorClosed
+	} else {
+		attachment.Color = attachmentColorOpen
+	}
+
+	attachment.Fields = attachmentFields(issue.Labels, issue.Assignees, nil)
+
+	return attachment
+}
+
+// attachmentFields returns the labels, assignees and reviewers fields of an attachment.
+// Empty fields are omitted.
+func attachmentFields(labels []*github.Label, assignees, reviewers []*github.User) []*model.SlackAttachmentField {
+	var fields []*model.SlackAttachmentField
+
+	if len(labels) > 0 {
+		names := make([]string, len(labels))
+		for i, label := range labels {
+			names[i] = "`" + label.GetName() + "`"
+		}
+		fields = append(fields, &model.SlackAttachmentField{Title: "Labels", Value: strings.Join(names, ", "), Short: true})
+	}
+
+	if len(assignees) > 0 {
+		fields = append(fields, &model.SlackAttachmentField{Title: "Assignees", Value: attachmentUserList(assignees), Short: true})
+	}
+
+	if len(reviewers) > 0 {
+		fields = append(fields, &model.SlackAttachmentField{Title: "Reviewers", Value: attachmentUserList(reviewers), Short: true})
+	}
+
+	return fields
+}
+
+// attachmentUserList renders users the same way the user template does: linked Mattermost
+// users are at-mentioned, everyone else links to their GitHub profile.
+func attachmentUserList(users []*github.User) string {
+	names := make([]string, len(users))
+	for i, user := range users {
+		if username := lookupMattermostUsername(user.GetLogin()); username != "" {
+			names[i] = "@" + username
+			continue
+		}
+		names[i] = fmt.Sprintf("[%s](%s)", user.GetLogin(), user.GetHTMLURL())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPullRequestAttachment(t *testing.T) {
+	for name, test := range map[string]struct {
+		pr            github.PullRequest
+		expectedColor string
+	}{
+		"open": {
+			pr:            github.PullRequest{State: sToP("open")},
+			expectedColor: attachmentColorOpen,
+		},
+		"merged": {
+			pr:            github.PullRequest{State: sToP("closed"), Merged: bToP(true)},
+			expectedColor: attachmentColorMerged,
+		},
+		"closed": {
+			pr:            github.PullRequest{State: sToP("closed"), Merged: bToP(false)},
+			expectedColor: attachmentColorClosed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pr := pullRequestWithLabelAndAssignee
+			pr.State = test.pr.State
+			pr.Merged = test.pr.Merged
+
+			attachment := newPullRequestAttachment(&repo, &pr, &user, "text")
+
+			assert.Equal(t, test.expectedColor, attachment.Color)
+			assert.Equal(t, "mattermost-plugin-github#42 Leverage git-get-head", attachment.Title)
+			assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-github/pull/42", attachment.TitleLink)
+			assert.Equal(t, "panda", attachment.AuthorName)
+			assert.Equal(t, "text", attachment.Text)
+		})
+	}
+}
+
+func TestAttachmentFields(t *testing.T) {
+	t.Run("no fields", func(t *testing.T) {
+		assert.Empty(t, attachmentFields(nil, nil, nil))
+	})
+
+	t.Run("labels, assignees and reviewers", withGitHubUserNameMapping(func(t *testing.T) {
+		unknown := github.User{
+			Login:   sToP("unknown-user"),
+			HTMLURL: sToP("https://github.com/unknown-user"),
+		}
+
+		fields := attachmentFields(labels, []*github.User{&user}, []*github.User{&user, &unknown})
+
+		require.Len(t, fields, 3)
+		assert.Equal(t, &model.SlackAttachmentField{Title: "Labels", Value: "`Help Wanted`, `Tech/Go`", Short: true}, fields[0])
+		assert.Equal(t, &model.SlackAttachmentField{Title: "Assignees", Value: "@pandabot", Short: true}, fields[1])
+		assert.Equal(t, &model.SlackAttachmentField{Title: "Reviewers", Value: "@pandabot, [unknown-user](https://github.com/unknown-user)", Short: true}, fields[2])
+	}))
+}
+
+func TestGetRenderStyle(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{NotificationRenderStyle: renderStyleAttachment})
+
+	assert.Equal(t, renderStyleAttachment, p.getRenderStyle(&Subscription{}))
+	assert.Equal(t, renderStyleMarkdown, p.getRenderStyle(&Subscription{Flags: SubscriptionFlags{RenderStyle: renderStyleMarkdown}}))
+
+	p.setConfiguration(&Configuration{})
+	assert.Equal(t, renderStyleMarkdown, p.getRenderStyle(&Subscription{}))
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+)
+
+const channelDefaultRepoKeySuffix = "_defaultrepo"
+
+// getStoredChannelDefaultRepo returns the default repository set with /github channel set-repo, if any.
+func (p *Plugin) getStoredChannelDefaultRepo(channelID string) (string, error) {
+	value, appErr := p.API.KVGet(channelID + channelDefaultRepoKeySuffix)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get the default repository of the channel")
+	}
+
+	return string(value), nil
+}
+
+func (p *Plugin) setChannelDefaultRepo(channelID, repo string) error {
+	if repo == "" {
+		if appErr := p.API.KVDelete(channelID + channelDefaultRepoKeySuffix); appErr != nil {
+			return errors.Wrap(appErr, "could not delete the default repository of the channel")
+		}
+		return nil
+	}
+
+	if appErr := p.API.KVSet(channelID+channelDefaultRepoKeySuffix, []byte(repo)); appErr != nil {
+		return errors.Wrap(appErr, "could not store the default repository of the channel")
+	}
+
+	return nil
+}
+
+func (p *Plugin) handleChannel(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) == 0 {
+		return "Invalid channel command. Available commands are 'set-repo' and 'unset-repo'."
+	}
+
+	command := parameters[0]
+	parameters = parameters[1:]
+
+	switch {
+	case command == "set-repo":
+		return p.handleChannelSetRepo(args, parameters, userInfo)
+	case command == "unset-repo":
+		return p.handleChannelUnsetRepo(args)
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", command)
+	}
+}
+
+func (p *Plugin) handleChannelSetRepo(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) == 0 {
+		owner, repo := p.getChannelDefaultRepo(args.ChannelId)
+		if repo == "" {
+			return "This channel has no default repository. Set one with `/github channel set-repo owner/repo`."
+		}
+		return fmt.Sprintf("The default repository of this channel is [%s](%s%s).", fullNameFromOwnerAndRepo(owner, repo), p.getBaseURL(), fullNameFromOwnerAndRepo(owner, repo))
+	}
+
+	owner, repo := parseOwnerAndRepo(parameters[0], p.getBaseURL())
+	if len(parameters) != 1 || repo == "" || isRepositoryPattern(repo) {
+		return "Please specify a repository, e.g. `/github channel set-repo owner/repo`."
+	}
+
+	if err := p.checkOrg(owner); err != nil {
+		return err.Error()
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	ghRepo, resp, err := githubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "Failed to set the default repository: " + getActionFailReason(resp, err, "view repositories", fullNameFromOwnerAndRepo(owner, repo), userInfo.GitHubUsername)
+	}
+
+	if err := p.setChannelDefaultRepo(args.ChannelId, ghRepo.GetFullName()); err != nil {
+		p.API.LogWarn("Failed to set the default repository", "channel_id", args.ChannelId, "error", err.Error())
+		return "Encountered an error trying to set the default repository. Please try again."
+	}
+
+	return fmt.Sprintf("[%s](%s) is now the default repository of this channel. Commands without a repository and references like `#123` use it.", ghRepo.GetFullName(), ghRepo.GetHTMLURL())
+}
+
+func (p *Plugin) handleChannelUnsetRepo(args *model.CommandArgs) string {
+	if err := p.setChannelDefaultRepo(args.ChannelId, ""); err != nil {
+		p.API.LogWarn("Failed to unset the default repository", "channel_id", args.ChannelId, "error", err.Error())
+		return "Encountered an error trying to unset the default repository. Please try again."
+	}
+
+	return "This channel no longer has a default repository."
+}
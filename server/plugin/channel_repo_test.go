@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelDefaultRepo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name": "mattermost/mattermost-server", "html_url": "https://github.com/mattermost/mattermost-server"}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/unknown", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "channel", Repository: "mattermost/mattermost-webapp"},
+	})
+	userInfo := serveTestGitHub(t, p, nil, mux)
+
+	run := func(parameters ...string) string {
+		return p.handleChannel(nil, &model.CommandArgs{UserId: "user", ChannelId: "channel"}, parameters, userInfo)
+	}
+
+	owner, repo := p.getChannelDefaultRepo("channel")
+	assert.Empty(t, repo, "subscriptions don't make a default repository")
+
+	msg := run("set-repo", "mattermost/mattermost-server")
+	assert.Equal(t, "[mattermost/mattermost-server](https://github.com/mattermost/mattermost-server) is now the default repository of this channel. Commands without a repository and references like `#123` use it.", msg)
+
+	owner, repo, number, err := p.parseIssueCommandRef("channel", "#12")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"mattermost", "mattermost-server", 12}, []interface{}{owner, repo, number})
+
+	assert.Contains(t, run("set-repo"), "The default repository of this channel is [mattermost/mattermost-server]")
+	assert.Contains(t, run("set-repo", "mattermost/unknown"), "Failed to set the default repository")
+	assert.Equal(t, "Please specify a repository, e.g. `/github channel set-repo owner/repo`.", run("set-repo", "mattermost"))
+
+	assert.Equal(t, "This channel no longer has a default repository.", run("unset-repo"))
+	_, repo = p.getChannelDefaultRepo("channel")
+	assert.Empty(t, repo)
+}
@@ -3,6 +3,10 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -12,6 +16,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	listAllFlag = "all"
+
+	// subscriptionsListPageSize is the number of subscriptions shown per page by /github subscriptions list --all.
+	subscriptionsListPageSize = 20
+)
+
 const (
 	featureIssueCreation = "issue_creations"
 	featureIssues        = "issues"
@@ -67,6 +78,71 @@ func validateFeatures(features []string) (bool, []string) {
 	return valid, invalidFeatures
 }
 
+// isFeatureList reports whether text is a list of features rather than a repository or organization.
+// Organizations named like a feature can still be given with a trailing slash, e.g. pulls/.
+func isFeatureList(text string) bool {
+	if strings.Contains(text, "/") {
+		return false
+	}
+
+	valid, _ := validateFeatures(strings.Split(text, ","))
+	return valid
+}
+
+// parseSubscriptionOptions parses the optional features and flags of a subscription,
+// falling back to the default features if none are given.
+func parseSubscriptionOptions(parameters []string) (string, SubscriptionFlags, error) {
+	features := "pulls,issues,creates,deletes"
+	flags := SubscriptionFlags{}
+
+	var optionList []string
+	var valueFlag string
+
+	for _, element := range parameters {
+		switch {
+		case valueFlag != "":
+			if err := flags.SetFlagValue(valueFlag, element); err != nil {
+				return "", flags, err
+			}
+			valueFlag = ""
+		case isFlag(element):
+			flag := parseFlag(element)
+			if flagTakesValue(flag) {
+				valueFlag = flag
+				continue
+			}
+			flags.AddFlag(flag)
+		default:
+			optionList = append(optionList, element)
+		}
+	}
+	if valueFlag != "" {
+		return "", flags, errors.Errorf("Please specify a value for --%s.", valueFlag)
+	}
+	if len(optionList) > 1 {
+		return "", flags, errors.New("Just one list of features is allowed")
+	} else if len(optionList) == 1 {
+		features = optionList[0]
+		fs := strings.Split(features, ",")
+		if SliceContainsString(fs, featureIssues) && SliceContainsString(fs, featureIssueCreation) {
+			return "", flags, errors.New("Feature list cannot contain both issue and issue_creations")
+		}
+		if SliceContainsString(fs, featurePulls) && SliceContainsString(fs, featurePullsMerged) {
+			return "", flags, errors.New("Feature list cannot contain both pulls and pulls_merged")
+		}
+		ok, ifs := validateFeatures(fs)
+		if !ok {
+			msg := fmt.Sprintf("Invalid feature(s) provided: %s", strings.Join(ifs, ","))
+			if len(ifs) == 0 {
+				msg = "Feature list must have \"pulls\" or \"issues\" when using a label."
+			}
+			return "", flags, errors.New(msg)
+		}
+	}
+
+	return features, flags, nil
+}
+
 func (p *Plugin) getCommand(config *Configuration) (*model.Command, error) {
 	iconData, err := command.GetIconData(p.API, "assets/icon-bg.svg")
 	if err != nil {
@@ -76,7 +152,7 @@ func (p *Plugin) getCommand(config *Configuration) (*model.Command, error) {
 	return &model.Command{
 		Trigger:              "github",
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Available commands: connect, disconnect, todo, me, settings, subscribe, unsubscribe, mute, help, issue",
+		AutoCompleteDesc:     "Available commands: connect, disconnect, todo, me, settings, subscribe, unsubscribe, mute, help, issue, watch, pr, channel",
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     getAutocompleteData(config),
 		AutocompleteIconData: iconData,
@@ -223,7 +299,7 @@ func (p *Plugin) handleSubscribe(c *plugin.Context, args *model.CommandArgs, par
 
 func (p *Plugin) handleSubscriptions(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	if len(parameters) == 0 {
-		return "Invalid subscribe command. Available commands are 'list', 'add' and 'delete'."
+		return "Invalid subscribe command. Available commands are 'list', 'add', 'delete', 'export', 'import' and 'history'."
 	}
 
 	command := parameters[0]
@@ -236,12 +312,22 @@ func (p *Plugin) handleSubscriptions(c *plugin.Context, args *model.CommandArgs,
 		return p.handleSubscribesAdd(c, args, parameters, userInfo)
 	case command == "delete":
 		return p.handleUnsubscribe(c, args, parameters, userInfo)
+	case command == "export":
+		return p.handleSubscriptionsExport(c, args, parameters, userInfo)
+	case command == "import":
+		return p.handleSubscriptionsImport(c, args, parameters, userInfo)
+	case command == "history":
+		return p.handleSubscriptionsHistory(c, args, parameters, userInfo)
 	default:
 		return fmt.Sprintf("Unknown subcommand %v", command)
 	}
 }
 
-func (p *Plugin) handleSubscriptionsList(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+func (p *Plugin) handleSubscriptionsList(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) > 0 && parameters[0] == "--"+listAllFlag {
+		return p.handleSubscriptionsListAll(c, args, parameters[1:], userInfo)
+	}
+
 	txt := ""
 	subs, err := p.GetSubscriptionsByChannel(args.ChannelId)
 	if err != nil {
@@ -257,104 +343,269 @@ func (p *Plugin) handleSubscriptionsList(_ *plugin.Context, args *model.CommandA
 		subFlags := sub.Flags.String()
 		txt += fmt.Sprintf("* `%s` - %s", strings.Trim(sub.Repository, "/"), sub.Features)
 		if subFlags != "" {
-			txt += fmt.Sprintf(" %s", subFlags)
+			txt += fmt.Sprintf(" %s", getCodeSpan(subFlags))
 		}
 		txt += "\n"
 	}
 
-	excludeRepos, err := p.GetExcludedNotificationRepos()
-	if err != nil {
-		return err.Error()
+	return txt
+}
+
+// getCodeSpan wraps text in a code span, so that patterns like payments-* aren't rendered as markdown.
+// Pipes are escaped, so that the code span can be used in a table. Empty text is returned as is.
+func getCodeSpan(text string) string {
+	if text == "" {
+		return ""
 	}
-	for _, repo := range excludeRepos {
-		txt += fmt.Sprintf("* `%s` - %s", strings.Trim(repo, "/"), "notification : disabled")
-		txt += "\n"
+
+	return "`" + strings.ReplaceAll(text, "|", "\\|") + "`"
+}
+
+// subscriptionListEntry is a subscription of /github subscriptions list --all with its channel, team and creator resolved.
+type subscriptionListEntry struct {
+	*Subscription
+	Team    string
+	Channel string
+	Creator string
+}
+
+// subscriptionListFilter restricts the subscriptions of /github subscriptions list --all.
+// Empty fields match everything.
+type subscriptionListFilter struct {
+	Owner   string
+	Repo    string
+	Team    string
+	Creator string
+}
+
+// matchesRepository reports whether events of the filtered repository are posted for sub.
+// Organization subscriptions match all repositories of the organization they don't exclude,
+// and repository pattern subscriptions all repositories matching the pattern.
+// It is checked before resolving channels and creators, which is more expensive.
+func (f *subscriptionListFilter) matchesRepository(sub *Subscription) bool {
+	owner, repo := parseOwnerAndRepo(sub.Repository, "")
+	if f.Owner != "" && !strings.EqualFold(f.Owner, owner) {
+		return false
 	}
-	return txt
+	if f.Repo == "" {
+		return true
+	}
+	if repo == "" {
+		return !sub.Excludes(fullNameFromOwnerAndRepo(f.Owner, f.Repo))
+	}
+	if isRepositoryPattern(repo) && !isRepositoryPattern(f.Repo) {
+		matched, _ := path.Match(repo, strings.ToLower(f.Repo))
+		return matched
+	}
+
+	return strings.EqualFold(f.Repo, repo)
 }
 
-func (p *Plugin) handleSubscribesAdd(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
-	if len(parameters) == 0 {
-		return "Please specify a repository."
+// matches reports whether the team and creator of entry match the filter.
+func (f *subscriptionListFilter) matches(entry *subscriptionListEntry) bool {
+	if f.Team != "" && !strings.EqualFold(f.Team, entry.Team) {
+		return false
+	}
+	if f.Creator != "" && !strings.EqualFold(f.Creator, entry.Creator) {
+		return false
 	}
 
-	features := "pulls,issues,creates,deletes"
-	flags := SubscriptionFlags{}
+	return true
+}
 
-	var excludeRepo string
-	if len(parameters) > 1 {
-		var optionList []string
-
-		for _, element := range parameters[1:] {
-			switch {
-			case isFlag(element):
-				flags.AddFlag(parseFlag(element))
-			case flags.ExcludeOrgRepos && excludeRepo == "":
-				excludeRepo = element
-			default:
-				optionList = append(optionList, element)
+// getSubscriptionListEntries resolves the team, channel and creator of each subscription.
+// Lookups are cached, as most servers have many subscriptions per channel and creator.
+func (p *Plugin) getSubscriptionListEntries(subs []*Subscription) []*subscriptionListEntry {
+	channels := map[string]*model.Channel{}
+	teams := map[string]string{}
+	creators := map[string]string{}
+
+	entries := make([]*subscriptionListEntry, 0, len(subs))
+	for _, sub := range subs {
+		entry := &subscriptionListEntry{Subscription: sub, Channel: sub.ChannelID, Creator: sub.CreatorID}
+
+		channel, ok := channels[sub.ChannelID]
+		if !ok {
+			channel, _ = p.API.GetChannel(sub.ChannelID)
+			channels[sub.ChannelID] = channel
+		}
+		if channel != nil {
+			entry.Channel = channel.Name
+			if channel.TeamId != "" {
+				if _, ok := teams[channel.TeamId]; !ok {
+					if team, appErr := p.API.GetTeam(channel.TeamId); appErr == nil {
+						teams[channel.TeamId] = team.Name
+					}
+				}
+				entry.Team = teams[channel.TeamId]
 			}
 		}
-		if len(optionList) > 1 {
-			return "Just one list of features is allowed"
-		} else if len(optionList) == 1 {
-			features = optionList[0]
-			fs := strings.Split(features, ",")
-			if SliceContainsString(fs, featureIssues) && SliceContainsString(fs, featureIssueCreation) {
-				return "Feature list cannot contain both issue and issue_creations"
+
+		if sub.CreatorID != "" {
+			if _, ok := creators[sub.CreatorID]; !ok {
+				creators[sub.CreatorID] = sub.CreatorID
+				if user, appErr := p.API.GetUser(sub.CreatorID); appErr == nil {
+					creators[sub.CreatorID] = user.Username
+				}
 			}
-			if SliceContainsString(fs, featurePulls) && SliceContainsString(fs, featurePullsMerged) {
-				return "Feature list cannot contain both pulls and pulls_merged"
+			entry.Creator = creators[sub.CreatorID]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func (p *Plugin) handleSubscriptionsListAll(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	isSysAdmin, err := p.isAuthorizedSysAdmin(args.UserId)
+	if err != nil {
+		p.API.LogWarn("Failed to check if user is System Admin", "error", err.Error())
+		return "Error checking user's permissions"
+	}
+	if !isSysAdmin {
+		return "Only System Admins are allowed to list the subscriptions of all channels."
+	}
+
+	filter := subscriptionListFilter{}
+	page := 1
+	for i := 0; i < len(parameters); i++ {
+		flag := parseFlag(parameters[i])
+		if !isFlag(parameters[i]) || i+1 == len(parameters) {
+			return fmt.Sprintf("Unknown parameter %s. Supported filters are --repo, --team, --creator and --page.", parameters[i])
+		}
+		i++
+		value := parameters[i]
+
+		switch flag {
+		case "repo":
+			filter.Owner, filter.Repo = parseOwnerAndRepo(value, p.getBaseURL())
+		case "team":
+			filter.Team = strings.TrimPrefix(value, "~")
+		case "creator":
+			filter.Creator = strings.TrimPrefix(value, "@")
+		case "page":
+			page, err = strconv.Atoi(value)
+			if err != nil || page < 1 {
+				return fmt.Sprintf("Invalid page %s.", value)
 			}
-			ok, ifs := validateFeatures(fs)
-			if !ok {
-				msg := fmt.Sprintf("Invalid feature(s) provided: %s", strings.Join(ifs, ","))
-				if len(ifs) == 0 {
-					msg = "Feature list must have \"pulls\" or \"issues\" when using a label."
-				}
-				return msg
+		default:
+			return fmt.Sprintf("Unknown parameter %s. Supported filters are --repo, --team, --creator and --page.", parameters[i-1])
+		}
+	}
+
+	allSubs, err := p.GetSubscriptions()
+	if err != nil {
+		return err.Error()
+	}
+
+	var subs []*Subscription
+	for _, repoSubs := range allSubs.Repositories {
+		for _, sub := range repoSubs {
+			if filter.matchesRepository(sub) {
+				subs = append(subs, sub)
 			}
 		}
 	}
 
+	var entries []*subscriptionListEntry
+	for _, entry := range p.getSubscriptionListEntries(subs) {
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		return "There are no matching subscriptions."
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Repository != entries[j].Repository {
+			return entries[i].Repository < entries[j].Repository
+		}
+		if entries[i].Team != entries[j].Team {
+			return entries[i].Team < entries[j].Team
+		}
+		return entries[i].Channel < entries[j].Channel
+	})
+
+	pages := (len(entries) + subscriptionsListPageSize - 1) / subscriptionsListPageSize
+	if page > pages {
+		return fmt.Sprintf("There are only %d page(s) of matching subscriptions.", pages)
+	}
+	start := (page - 1) * subscriptionsListPageSize
+	end := start + subscriptionsListPageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	txt := fmt.Sprintf("### Subscriptions of all channels (%d)\n", len(entries))
+	txt += "| Repository | Team | Channel | Creator | Features | Flags |\n"
+	txt += "|:-----------|:-----|:--------|:--------|:---------|:------|\n"
+	for _, entry := range entries[start:end] {
+		txt += fmt.Sprintf("| `%s` | %s | ~%s | @%s | %s | %s |\n",
+			strings.Trim(entry.Repository, "/"), entry.Team, entry.Channel, entry.Creator,
+			getCodeSpan(entry.Features), getCodeSpan(entry.Flags.String()))
+	}
+
+	if pages > 1 {
+		txt += fmt.Sprintf("\nPage %d of %d.", page, pages)
+		if page < pages {
+			txt += fmt.Sprintf(" Use `--page %d` to see more.", page+1)
+		}
+	}
+
+	return txt
+}
+
+func (p *Plugin) handleSubscribesAdd(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	// Without a repository, e.g. /github subscriptions add issues,pulls --render-style markdown,
+	// the default repository of the channel is used.
+	if len(parameters) == 0 || isFlag(parameters[0]) || isFeatureList(parameters[0]) {
+		owner, repo := p.getChannelDefaultRepo(args.ChannelId)
+		if repo == "" {
+			return "Please specify a repository."
+		}
+		parameters = append([]string{fullNameFromOwnerAndRepo(owner, repo)}, parameters...)
+	}
+
+	features, flags, err := parseSubscriptionOptions(parameters[1:])
+	if err != nil {
+		return err.Error()
+	}
+
 	ctx := context.Background()
 	githubClient := p.githubConnectUser(ctx, userInfo)
 
 	owner, repo := parseOwnerAndRepo(parameters[0], p.getBaseURL())
 	if repo == "" {
+		flags.qualifyExcludeRepos(owner)
 		if err := p.SubscribeOrg(ctx, githubClient, args.UserId, owner, args.ChannelId, features, flags); err != nil {
 			return err.Error()
 		}
 		orgLink := p.getBaseURL() + owner
 		var subOrgMsg = fmt.Sprintf("Successfully subscribed to organization [%s](%s).", owner, orgLink)
-		if flags.ExcludeOrgRepos {
-			var excludeMsg string
-			for _, value := range strings.Split(excludeRepo, ",") {
-				val := strings.TrimSpace(value)
-				notificationOffRepoOwner, NotificationOffRepo := parseOwnerAndRepo(val, p.getBaseURL())
-				if notificationOffRepoOwner != owner {
-					return fmt.Sprintf("--exclude repository  %s is not of subscribed organization .", NotificationOffRepo)
-				}
-				if err := p.StoreExcludedNotificationRepo(val); err != nil {
-					return err.Error()
-				}
-				if excludeMsg != "" {
-					excludeMsg += fmt.Sprintf(" and [%s](%s)", NotificationOffRepo, orgLink+"/"+NotificationOffRepo)
-					continue
-				}
-				excludeMsg += fmt.Sprintf("[%s](%s)", NotificationOffRepo, orgLink+"/"+NotificationOffRepo)
+		if len(flags.ExcludeRepos) > 0 {
+			excluded := make([]string, 0, len(flags.ExcludeRepos))
+			for _, pattern := range flags.ExcludeRepos {
+				excluded = append(excluded, "`"+pattern+"`")
 			}
-			subOrgMsg += "\n\n" + fmt.Sprintf("Notifications are disabled for %s", excludeMsg)
+			subOrgMsg += "\n\n" + fmt.Sprintf("Notifications are disabled in this channel for %s.", strings.Join(excluded, ", "))
+		}
+		switch {
+		case flags.Topic != "":
+			subOrgMsg += "\n\n" + fmt.Sprintf("Only repositories with the topic `%s` are posted in this channel.", flags.Topic)
+		case flags.Team != "":
+			subOrgMsg += "\n\n" + fmt.Sprintf("Only repositories of the team `%s` are posted in this channel.", flags.Team)
 		}
 		return subOrgMsg
 	}
-	if flags.ExcludeOrgRepos {
-		return "--exclude feature currently support on organization level."
-	}
-
 	if err := p.Subscribe(ctx, githubClient, args.UserId, owner, repo, args.ChannelId, features, flags); err != nil {
 		return err.Error()
 	}
+	if isRepositoryPattern(repo) {
+		return fmt.Sprintf("Successfully subscribed to repositories matching `%s`.", strings.ToLower(fullNameFromOwnerAndRepo(owner, repo)))
+	}
 	repoLink := p.getBaseURL() + owner + "/" + repo
 
 	msg := fmt.Sprintf("Successfully subscribed to [%s](%s).", repo, repoLink)
@@ -376,11 +627,7 @@ func (p *Plugin) handleUnsubscribe(_ *plugin.Context, args *model.CommandArgs, p
 
 	repo := parameters[0]
 
-	if err := p.EnableNotificationTurnedOffRepo(repo); err != nil {
-		p.API.LogWarn("Failed to unsubscribe while removing repo from disable notification list", "repo", repo, "error", err.Error())
-		return "Encountered an error trying to remove from notify disabled list. Please try again."
-	}
-	if err := p.Unsubscribe(args.ChannelId, repo); err != nil {
+	if err := p.Unsubscribe(args.ChannelId, repo, args.UserId); err != nil {
 		p.API.LogWarn("Failed to unsubscribe", "repo", repo, "error", err.Error())
 		return "Encountered an error trying to unsubscribe. Please try again."
 	}
@@ -493,7 +740,7 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 
 func (p *Plugin) handleIssue(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	if len(parameters) == 0 {
-		return "Invalid issue command. Available command is 'create'."
+		return "Invalid issue command. Available commands are 'create', 'close', 'reopen', 'comment', 'assign', 'label', 'link' and 'unlink'."
 	}
 
 	command := parameters[0]
@@ -501,13 +748,102 @@ func (p *Plugin) handleIssue(_ *plugin.Context, args *model.CommandArgs, paramet
 
 	switch {
 	case command == "create":
-		p.openIssueCreateModal(args.UserId, args.ChannelId, strings.Join(parameters, " "))
-		return ""
+		return p.handleIssueCreate(args, parameters, userInfo)
+	case command == "close":
+		return p.handleIssueStateChange(args, parameters, userInfo, "closed")
+	case command == "reopen":
+		return p.handleIssueStateChange(args, parameters, userInfo, "open")
+	case command == "comment":
+		return p.handleIssueComment(args, parameters, userInfo)
+	case command == "assign":
+		return p.handleIssueAssign(args, parameters, userInfo)
+	case command == "label":
+		return p.handleIssueLabel(args, parameters, userInfo)
+	case command == "link":
+		return p.handleIssueLink(args, parameters, userInfo)
+	case command == "unlink":
+		return p.handleIssueUnlink(args)
 	default:
 		return fmt.Sprintf("Unknown subcommand %v", command)
 	}
 }
 
+func (p *Plugin) handleIssueLink(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) != 1 {
+		return "Please specify an issue or pull request, e.g. `/github issue link owner/repo#1`."
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return "Please specify an issue or pull request, e.g. `/github issue link owner/repo#1`."
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	if _, resp, err := githubClient.Issues.Get(ctx, owner, repo, number); err != nil {
+		statusCode := http.StatusInternalServerError
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		return "Failed to link the thread: " + getFailReason(statusCode, fullNameFromOwnerAndRepo(owner, repo), userInfo.GitHubUsername)
+	}
+
+	rootID := args.RootId
+	if rootID == "" {
+		root, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: args.ChannelId,
+			Message:   fmt.Sprintf("Discussion of [%s#%d](%s%s/issues/%d)", fullNameFromOwnerAndRepo(owner, repo), number, p.getBaseURL(), fullNameFromOwnerAndRepo(owner, repo), number),
+		})
+		if appErr != nil {
+			p.API.LogWarn("Failed to create thread for link", "error", appErr.Error())
+			return "Failed to create a thread to link."
+		}
+		rootID = root.Id
+	}
+
+	link := &ThreadLink{
+		RootID:    rootID,
+		ChannelID: args.ChannelId,
+		Owner:     owner,
+		Repo:      repo,
+		Number:    number,
+		CreatorID: args.UserId,
+	}
+	if err := p.LinkThread(link); err != nil {
+		p.API.LogWarn("Failed to link thread", "error", err.Error())
+		return "Failed to link the thread."
+	}
+
+	if err := p.createLinkAnnouncements(ctx, githubClient, link); err != nil {
+		p.API.LogWarn("Failed to announce thread link", "error", err.Error())
+	}
+
+	return ""
+}
+
+func (p *Plugin) handleIssueUnlink(args *model.CommandArgs) string {
+	if args.RootId == "" {
+		return "Please run this command in the thread you want to unlink."
+	}
+
+	link, err := p.getThreadLink(args.RootId)
+	if err != nil {
+		p.API.LogWarn("Failed to get thread link", "error", err.Error())
+		return "Failed to unlink the thread."
+	}
+	if link == nil {
+		return "This thread is not linked to an issue or pull request."
+	}
+
+	if err := p.UnlinkThread(args.RootId); err != nil {
+		p.API.LogWarn("Failed to unlink thread", "error", err.Error())
+		return "Failed to unlink the thread."
+	}
+
+	return fmt.Sprintf("This thread is no longer linked to [%s](%s).", link.IssueName(), link.IssueURL(p.getBaseURL()))
+}
+
 type CommandHandleFunc func(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string
 
 func (p *Plugin) isAuthorizedSysAdmin(userID string) (bool, error) {
@@ -629,9 +965,17 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	todo := model.NewAutocompleteData("todo", "", "Get a list of unread messages and pull requests awaiting your review")
 	github.AddCommand(todo)
 
-	subscriptions := model.NewAutocompleteData("subscriptions", "[command]", "Available commands: list, add, delete")
+	subscriptions := model.NewAutocompleteData("subscriptions", "[command]", "Available commands: list, add, delete, export, import, history")
 
-	subscribeList := model.NewAutocompleteData("list", "", "List the current channel subscriptions")
+	subscribeList := model.NewAutocompleteData("list", "[--all]", "List the current channel subscriptions")
+	listAll := []model.AutocompleteListItem{
+		{
+			HelpText: "System Admins only: list the subscriptions of all channels, filtered by --repo, --team or --creator and paged with --page",
+			Hint:     "(optional)",
+			Item:     "--all",
+		},
+	}
+	subscribeList.AddStaticListArgument("Currently supports --all", false, listAll)
 	subscriptions.AddCommand(subscribeList)
 
 	subscriptionsAdd := model.NewAutocompleteData("add", "[owner/repo] [features] [flags]", "Subscribe the current channel to receive notifications about opened pull requests and issues for an organization or repository. [features] and [flags] are optional arguments")
@@ -640,7 +984,7 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	if config.GitHubOrg != "" {
 		exclude := []model.AutocompleteListItem{
 			{
-				HelpText: "notifications for these repos will be turned off",
+				HelpText: "Comma-delimited repositories or glob patterns of the organization whose events will not be posted in this channel",
 				Hint:     "(optional)",
 				Item:     "--exclude",
 			},
@@ -656,12 +1000,35 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 		}
 		subscriptionsAdd.AddStaticListArgument("Currently supports --exclude-org-member ", false, flags)
 	}
+	renderStyles := []model.AutocompleteListItem{
+		{
+			HelpText: "Render events for this subscription as markdown",
+			Item:     renderStyleMarkdown,
+		},
+		{
+			HelpText: "Render events for this subscription as message attachments",
+			Item:     renderStyleAttachment,
+		},
+	}
+	subscriptionsAdd.AddNamedStaticListArgument(renderStyleFlag, "Render events for this subscription as markdown or as message attachments", false, renderStyles)
+	subscriptionsAdd.AddNamedTextArgument(topicFlag, "Organization subscriptions only: post events of the repositories with this GitHub topic", "[topic]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(teamFlag, "Organization subscriptions only: post events of the repositories of the GitHub team with this slug", "[team]", "", false)
 	subscriptions.AddCommand(subscriptionsAdd)
 
 	subscriptionsDelete := model.NewAutocompleteData("delete", "[owner/repo]", "Unsubscribe the current channel from an organization or repository")
 	subscriptionsDelete.AddTextArgument("Owner/repo to unsubscribe from", "[owner/repo]", "")
 	subscriptions.AddCommand(subscriptionsDelete)
 
+	subscriptionsExport := model.NewAutocompleteData("export", "[--all] [--format json|yaml]", "Export the subscriptions of the current channel, or of all channels for System Admins, as a file")
+	subscriptions.AddCommand(subscriptionsExport)
+
+	subscriptionsImport := model.NewAutocompleteData("import", "[post link] [--apply]", "Import the subscriptions file attached to a post. Without --apply, only the changes are shown")
+	subscriptionsImport.AddTextArgument("Link to a post with a subscriptions file attached", "[post link]", "")
+	subscriptions.AddCommand(subscriptionsImport)
+
+	subscriptionsHistory := model.NewAutocompleteData("history", "", "Show who added, changed or deleted subscriptions of the current channel")
+	subscriptions.AddCommand(subscriptionsHistory)
+
 	github.AddCommand(subscriptions)
 
 	me := model.NewAutocompleteData("me", "", "Display the connected GitHub account")
@@ -714,14 +1081,85 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 
 	github.AddCommand(settings)
 
-	issue := model.NewAutocompleteData("issue", "[command]", "Available commands: create")
+	issue := model.NewAutocompleteData("issue", "[command]", "Available commands: create, close, reopen, comment, assign, label, link, unlink")
 
-	issueCreate := model.NewAutocompleteData("create", "[title]", "Open a dialog to create a new issue in Github, using the title if provided")
-	issueCreate.AddTextArgument("Title for the Github issue", "[title]", "")
+	issueCreate := model.NewAutocompleteData("create", "[--repo owner/repo] [title] [-- body]", "Open a dialog to create a new issue in Github, using the title if provided, or create it directly when flags are given")
+	issueCreate.AddTextArgument("Title for the Github issue. Flags: --repo, --label, --assignee, --milestone", "[--repo owner/repo] [title] [-- body]", "")
 	issue.AddCommand(issueCreate)
 
+	issueClose := model.NewAutocompleteData("close", "[owner/repo#number] [reason]", "Close an issue, posting the reason as a comment if provided")
+	issueClose.AddTextArgument("Issue, e.g. owner/repo#1, its URL, or #1 in a channel with a default repository", "[owner/repo#number]", "")
+	issue.AddCommand(issueClose)
+
+	issueReopen := model.NewAutocompleteData("reopen", "[owner/repo#number] [reason]", "Reopen an issue, posting the reason as a comment if provided")
+	issueReopen.AddTextArgument("Issue, e.g. owner/repo#1, its URL, or #1 in a channel with a default repository", "[owner/repo#number]", "")
+	issue.AddCommand(issueReopen)
+
+	issueComment := model.NewAutocompleteData("comment", "[owner/repo#number] [text]", "Comment on an issue or pull request")
+	issueComment.AddTextArgument("Issue, e.g. owner/repo#1, its URL, or #1 in a channel with a default repository", "[owner/repo#number]", "")
+	issue.AddCommand(issueComment)
+
+	issueAssign := model.NewAutocompleteData("assign", "[owner/repo#number] [@username]", "Assign Mattermost users or GitHub users to an issue or pull request")
+	issueAssign.AddTextArgument("Issue, e.g. owner/repo#1, its URL, or #1 in a channel with a default repository", "[owner/repo#number]", "")
+	issue.AddCommand(issueAssign)
+
+	issueLabel := model.NewAutocompleteData("label", "[owner/repo#number] [+label] [-label]", "Add labels prefixed with + and remove labels prefixed with -")
+	issueLabel.AddTextArgument("Issue, e.g. owner/repo#1, its URL, or #1 in a channel with a default repository", "[owner/repo#number]", "")
+	issue.AddCommand(issueLabel)
+
+	issueLink := model.NewAutocompleteData("link", "[owner/repo#number]", "Link the current thread to an issue or pull request and sync comments in both directions")
+	issueLink.AddTextArgument("Issue or pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	issue.AddCommand(issueLink)
+
+	issueUnlink := model.NewAutocompleteData("unlink", "", "Remove the link of the current thread")
+	issue.AddCommand(issueUnlink)
+
 	github.AddCommand(issue)
 
+	watch := model.NewAutocompleteData("watch", "[owner/repo#number]", "Post all events of an issue or pull request in the current channel or thread until it is closed")
+	watch.AddTextArgument("Issue or pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	watchList := model.NewAutocompleteData("list", "", "List the issues and pull requests watched in the current channel")
+	watch.AddCommand(watchList)
+	watchStop := model.NewAutocompleteData("stop", "[owner/repo#number]", "Stop watching an issue or pull request in the current channel or thread")
+	watchStop.AddTextArgument("Issue or pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	watch.AddCommand(watchStop)
+	github.AddCommand(watch)
+
+	pr := model.NewAutocompleteData("pr", "[command]", "Available commands: list, view, approve, merge, request-review")
+	prList := model.NewAutocompleteData("list", "[owner/repo] [--mine|--review-requested]", "List open pull requests of a repository, or the ones you opened or are requested to review")
+	prList.AddTextArgument("Owner/repo to list pull requests of", "[owner/repo] (optional)", "")
+	prList.AddStaticListArgument("Filter the pull requests", false, []model.AutocompleteListItem{
+		{HelpText: "Only pull requests you opened", Hint: "(optional)", Item: "--" + prListMineFlag},
+		{HelpText: "Only pull requests you are requested to review", Hint: "(optional)", Item: "--" + prListReviewRequestedFlag},
+	})
+	pr.AddCommand(prList)
+	prView := model.NewAutocompleteData("view", "[owner/repo#number]", "Show the state, reviewers and description of a pull request")
+	prView.AddTextArgument("Pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	pr.AddCommand(prView)
+	prApprove := model.NewAutocompleteData("approve", "[owner/repo#number] [comment]", "Approve a pull request, optionally with a comment")
+	prApprove.AddTextArgument("Pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	pr.AddCommand(prApprove)
+	prMerge := model.NewAutocompleteData("merge", "[owner/repo#number] [--squash|--rebase]", "Merge a pull request with a merge commit, or squash or rebase it")
+	prMerge.AddTextArgument("Pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	prMerge.AddStaticListArgument("Merge method", false, []model.AutocompleteListItem{
+		{HelpText: "Squash and merge", Hint: "(optional)", Item: "--squash"},
+		{HelpText: "Rebase and merge", Hint: "(optional)", Item: "--rebase"},
+	})
+	pr.AddCommand(prMerge)
+	prRequestReview := model.NewAutocompleteData("request-review", "[owner/repo#number] [@username]", "Request reviews on a pull request from Mattermost users or GitHub users")
+	prRequestReview.AddTextArgument("Pull request, e.g. owner/repo#1 or its URL", "[owner/repo#number]", "")
+	prRequestReview.AddTextArgument("Mattermost @usernames or GitHub usernames of the reviewers", "[@username]", "")
+	pr.AddCommand(prRequestReview)
+	github.AddCommand(pr)
+
+	channel := model.NewAutocompleteData("channel", "[command]", "Available commands: set-repo, unset-repo")
+	channelSetRepo := model.NewAutocompleteData("set-repo", "[owner/repo]", "Set the default repository of the channel, used by commands without a repository and by #number references")
+	channelSetRepo.AddTextArgument("Owner/repo to use as default", "[owner/repo]", "")
+	channel.AddCommand(channelSetRepo)
+	channelUnsetRepo := model.NewAutocompleteData("unset-repo", "", "Remove the default repository of the channel")
+	channel.AddCommand(channelUnsetRepo)
+	github.AddCommand(channel)
+
 	return github
 }
 
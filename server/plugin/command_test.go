@@ -2,9 +2,14 @@ package plugin
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateFeatures(t *testing.T) {
@@ -84,6 +89,14 @@ func TestValidateFeatures(t *testing.T) {
 	}
 }
 
+func TestIsFeatureList(t *testing.T) {
+	assert.True(t, isFeatureList("issues"))
+	assert.True(t, isFeatureList("issues,pulls,label:bug"))
+	assert.False(t, isFeatureList("mattermost"))
+	assert.False(t, isFeatureList("pulls/"))
+	assert.False(t, isFeatureList("mattermost/issues"))
+}
+
 func TestParseCommand(t *testing.T) {
 	type output struct {
 		command    string
@@ -197,3 +210,106 @@ func TestParseCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSubscriptionOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		features, flags, err := parseSubscriptionOptions(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "pulls,issues,creates,deletes", features)
+		assert.Equal(t, SubscriptionFlags{}, flags)
+	})
+
+	t.Run("features and flags", func(t *testing.T) {
+		features, flags, err := parseSubscriptionOptions([]string{"--exclude-org-member", `issues,label:"Help Wanted"`, "--render-style", "markdown"})
+		require.NoError(t, err)
+		assert.Equal(t, `issues,label:"Help Wanted"`, features)
+		assert.Equal(t, SubscriptionFlags{ExcludeOrgMembers: true, RenderStyle: "markdown"}, flags)
+	})
+
+	t.Run("missing flag value", func(t *testing.T) {
+		_, _, err := parseSubscriptionOptions([]string{"pulls", "--render-style"})
+		assert.EqualError(t, err, "Please specify a value for --render-style.")
+	})
+
+	t.Run("invalid feature", func(t *testing.T) {
+		_, _, err := parseSubscriptionOptions([]string{"pulls,unknown"})
+		assert.EqualError(t, err, "Invalid feature(s) provided: unknown")
+	})
+
+	t.Run("multiple feature lists", func(t *testing.T) {
+		_, _, err := parseSubscriptionOptions([]string{"pulls", "issues"})
+		assert.EqualError(t, err, "Just one list of features is allowed")
+	})
+}
+
+func TestSubscriptionListFilter(t *testing.T) {
+	orgSub := &Subscription{Repository: "mattermost/", Flags: SubscriptionFlags{ExcludeRepos: []string{"mattermost/sandbox-*"}}}
+	repoSub := &Subscription{Repository: "mattermost/mattermost-server"}
+	otherSub := &Subscription{Repository: "other/mattermost-server"}
+
+	filter := subscriptionListFilter{Owner: "Mattermost", Repo: "mattermost-server"}
+	assert.True(t, filter.matchesRepository(orgSub))
+	assert.True(t, filter.matchesRepository(repoSub))
+	assert.False(t, filter.matchesRepository(otherSub))
+
+	filter = subscriptionListFilter{Owner: "mattermost", Repo: "sandbox-1"}
+	assert.False(t, filter.matchesRepository(orgSub))
+	assert.False(t, filter.matchesRepository(repoSub))
+
+	filter = subscriptionListFilter{Owner: "mattermost"}
+	assert.True(t, filter.matchesRepository(orgSub))
+	assert.True(t, filter.matchesRepository(repoSub))
+
+	patternSub := &Subscription{Repository: "mattermost/mattermost-*"}
+	filter = subscriptionListFilter{Owner: "mattermost", Repo: "Mattermost-Server"}
+	assert.True(t, filter.matchesRepository(patternSub))
+	filter = subscriptionListFilter{Owner: "mattermost", Repo: "sandbox-1"}
+	assert.False(t, filter.matchesRepository(patternSub))
+	filter = subscriptionListFilter{Owner: "mattermost", Repo: "mattermost-*"}
+	assert.True(t, filter.matchesRepository(patternSub))
+
+	filter = subscriptionListFilter{Team: "Engineering", Creator: "alice"}
+	assert.True(t, filter.matches(&subscriptionListEntry{Subscription: repoSub, Team: "engineering", Creator: "alice"}))
+	assert.False(t, filter.matches(&subscriptionListEntry{Subscription: repoSub, Team: "engineering", Creator: "bob"}))
+	assert.False(t, filter.matches(&subscriptionListEntry{Subscription: repoSub, Team: "sales", Creator: "alice"}))
+}
+
+func TestHandleSubscriptionsListAll(t *testing.T) {
+	var subs []*Subscription
+	for i := 0; i < subscriptionsListPageSize+1; i++ {
+		subs = append(subs, &Subscription{ChannelID: fmt.Sprintf("channel%d", i), CreatorID: "creator", Repository: "mattermost/mattermost-server", Features: "pulls"})
+	}
+	subs = append(subs, &Subscription{ChannelID: "org", CreatorID: "creator", Repository: "mattermost/", Features: `pulls,label:"a|b"`,
+		Flags: SubscriptionFlags{ExcludeOrgRepos: true, ExcludeRepos: []string{"mattermost/payments-*", "mattermost/my_repo"}}})
+	p := pluginWithMockedSubs(subs)
+	api := p.API.(*plugintest.API)
+	api.On("GetUser", "admin").Return(&model.User{Id: "admin", Roles: "system_admin system_user"}, nil)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Roles: "system_user"}, nil)
+	api.On("GetUser", "creator").Return(&model.User{Id: "creator", Username: "alice"}, nil)
+	api.On("GetChannel", mock.Anything).Return(&model.Channel{Name: "town-square", TeamId: "team"}, nil)
+	api.On("GetTeam", "team").Return(&model.Team{Name: "engineering"}, nil)
+
+	t.Run("only for system admins", func(t *testing.T) {
+		msg := p.handleSubscriptionsList(nil, &model.CommandArgs{UserId: "user"}, []string{"--all"}, nil)
+		assert.Equal(t, "Only System Admins are allowed to list the subscriptions of all channels.", msg)
+	})
+
+	t.Run("paged", func(t *testing.T) {
+		msg := p.handleSubscriptionsList(nil, &model.CommandArgs{UserId: "admin"}, []string{"--all", "--creator", "@alice"}, nil)
+		assert.Contains(t, msg, "| `mattermost/mattermost-server` | engineering | ~town-square | @alice | `pulls` |  |")
+		assert.Contains(t, msg, "Page 1 of 2. Use `--page 2` to see more.")
+
+		msg = p.handleSubscriptionsList(nil, &model.CommandArgs{UserId: "admin"}, []string{"--all", "--page", "2"}, nil)
+		assert.Equal(t, 2, strings.Count(msg, "@alice"))
+	})
+
+	t.Run("features and flags are code", func(t *testing.T) {
+		msg := p.handleSubscriptionsList(nil, &model.CommandArgs{UserId: "admin"}, []string{"--all", "--repo", "mattermost"}, nil)
+		assert.Contains(t, msg, "| `mattermost` | engineering | ~town-square | @alice | `pulls,label:\"a\\|b\"` | `--exclude mattermost/payments-*,mattermost/my_repo` |")
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		msg := p.handleSubscriptionsList(nil, &model.CommandArgs{UserId: "admin"}, []string{"--all", "--team", "sales"}, nil)
+		assert.Equal(t, "There are no matching subscriptions.", msg)
+	})
+}
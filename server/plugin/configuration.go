@@ -30,8 +30,14 @@ type Configuration struct {
 	EnterpriseBaseURL           string
 	EnterpriseUploadURL         string
 	EnableCodePreview           string
+	EnableLinkPreview           string
+	EnableIssueReferences       string
 	EnableWebhookEventLogging   bool
 	UsePreregisteredApplication bool
+	NotificationRenderStyle     string
+	ReactionMapping             string
+	// EnableSubscriptionChangeMessages posts changes of subscriptions in the affected channel.
+	EnableSubscriptionChangeMessages bool
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -116,6 +122,12 @@ func (p *Plugin) OnConfigurationChange() error {
 	}
 
 	p.setConfiguration(configuration)
+	p.setGitHubRegexes(configuration)
+
+	// An invalid reaction mapping only disables the custom mapping, not the whole plugin.
+	if _, err := parseReactionMapping(configuration.ReactionMapping); err != nil {
+		p.API.LogWarn("Invalid reaction mapping, using the default one", "error", err.Error())
+	}
 
 	command, err := p.getCommand(configuration)
 	if err != nil {
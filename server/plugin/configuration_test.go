@@ -29,6 +29,14 @@ func TestIsValid(t *testing.T) {
 				UsePreregisteredApplication: false,
 			},
 		},
+		{
+			description: "valid configuration: invalid reaction mapping",
+			config: &Configuration{
+				EncryptionKey:               "abcd",
+				UsePreregisteredApplication: true,
+				ReactionMapping:             "tada:party",
+			},
+		},
 		{
 			description: "invalid configuration: custom OAuth app without credentials",
 			config: &Configuration{
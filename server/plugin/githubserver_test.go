@@ -0,0 +1,27 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// serveTestGitHub starts a GitHub Enterprise server answering with mux and sets the configuration
+// of p to config, pointed at the server. config may be nil. The server is closed when the test ends.
+// It returns a connected user whose GitHub client sends its requests to the server.
+func serveTestGitHub(t *testing.T, p *Plugin, config *Configuration, mux *http.ServeMux) *GitHubUserInfo {
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	if config == nil {
+		config = &Configuration{}
+	}
+	config.EncryptionKey = "0123456789abcdef"
+	config.EnterpriseBaseURL = server.URL + "/"
+	config.EnterpriseUploadURL = server.URL + "/"
+	p.setConfiguration(config)
+
+	return &GitHubUserInfo{UserID: "user", GitHubUsername: "octocat", Token: &oauth2.Token{AccessToken: "token"}, MM34646ResetTokenDone: true}
+}
@@ -0,0 +1,308 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+// getChannelDefaultRepo returns the repository set with /github channel set-repo, which commands without
+// a repository and #number references in the channel fall back to.
+func (p *Plugin) getChannelDefaultRepo(channelID string) (owner, repo string) {
+	defaultRepo, err := p.getStoredChannelDefaultRepo(channelID)
+	if err != nil {
+		p.API.LogWarn("Failed to get the default repository", "channel_id", channelID, "error", err.Error())
+		return "", ""
+	}
+
+	return parseOwnerAndRepo(defaultRepo, "")
+}
+
+// parseIssueCommandRef parses the issue or pull request reference of a /github issue command.
+// Besides the forms accepted by parseIssueRef, #number is resolved against the default repository of the channel.
+func (p *Plugin) parseIssueCommandRef(channelID, ref string) (owner, repo string, number int, err error) {
+	if !strings.HasPrefix(ref, "#") {
+		return parseIssueRef(ref, p.getBaseURL())
+	}
+
+	number, err = strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	if err != nil || number <= 0 {
+		return "", "", 0, errors.Errorf("invalid issue or pull request reference %q", ref)
+	}
+
+	owner, repo = p.getChannelDefaultRepo(channelID)
+	if repo == "" {
+		return "", "", 0, errors.New("this channel has no default repository")
+	}
+
+	return owner, repo, number, nil
+}
+
+// handleIssueStateChange closes or reopens an issue, posting the optional reason as a comment first.
+func (p *Plugin) handleIssueStateChange(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo, state string) string {
+	command := "close"
+	action := "close issues"
+	done := "You closed %s."
+	if state == "open" {
+		command = "reopen"
+		action = "reopen issues"
+		done = "You reopened %s."
+	}
+
+	if len(parameters) == 0 {
+		return fmt.Sprintf("Please specify an issue, e.g. `/github issue %s owner/repo#1 [reason]`.", command)
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return fmt.Sprintf("Please specify an issue, e.g. `/github issue %s owner/repo#1 [reason]`. #1 only works in channels with a default repository.", command)
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+	issueLink := fmt.Sprintf("[%s#%d](%s%s/issues/%d)", repoName, number, p.getBaseURL(), repoName, number)
+
+	if reason := strings.Join(parameters[1:], " "); reason != "" {
+		if _, resp, err := githubClient.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &reason}); err != nil {
+			return fmt.Sprintf("Failed to %s the issue: %s", command, getActionFailReason(resp, err, "comment", repoName, userInfo.GitHubUsername))
+		}
+	}
+
+	if _, resp, err := githubClient.Issues.Edit(ctx, owner, repo, number, &github.IssueRequest{State: &state}); err != nil {
+		return fmt.Sprintf("Failed to %s the issue: %s", command, getActionFailReason(resp, err, action, repoName, userInfo.GitHubUsername))
+	}
+
+	return fmt.Sprintf(done, issueLink)
+}
+
+func (p *Plugin) handleIssueComment(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify an issue and a comment, e.g. `/github issue comment owner/repo#1 Fixed in the latest release`."
+	if len(parameters) < 2 {
+		return usage
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return usage
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	body := strings.Join(parameters[1:], " ")
+	comment, resp, err := githubClient.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return "Failed to comment on the issue: " + getActionFailReason(resp, err, "comment", repoName, userInfo.GitHubUsername)
+	}
+
+	return fmt.Sprintf("You [commented](%s) on %s#%d.", comment.GetHTMLURL(), repoName, number)
+}
+
+func (p *Plugin) handleIssueAssign(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify an issue and assignees, e.g. `/github issue assign owner/repo#1 @user`."
+	if len(parameters) < 2 {
+		return usage
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return usage
+	}
+
+	assignees := make([]string, 0, len(parameters)-1)
+	for _, assignee := range parameters[1:] {
+		login, err := p.resolveGitHubLogin(assignee)
+		if err != nil {
+			return err.Error()
+		}
+		assignees = append(assignees, login)
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	if _, resp, err := githubClient.Issues.AddAssignees(ctx, owner, repo, number, assignees); err != nil {
+		return "Failed to assign the issue: " + getActionFailReason(resp, err, "assign issues", repoName, userInfo.GitHubUsername)
+	}
+
+	return fmt.Sprintf("You assigned %s to [%s#%d](%s%s/issues/%d).", strings.Join(assignees, ", "), repoName, number, p.getBaseURL(), repoName, number)
+}
+
+// handleIssueLabel adds labels prefixed with + or without prefix, and removes labels prefixed with -.
+func (p *Plugin) handleIssueLabel(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify an issue and labels to add or remove, e.g. `/github issue label owner/repo#1 +bug -triage`."
+	if len(parameters) < 2 {
+		return usage
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return usage
+	}
+
+	var add, remove []string
+	for _, label := range parameters[1:] {
+		switch {
+		case strings.HasPrefix(label, "-"):
+			remove = append(remove, strings.TrimPrefix(label, "-"))
+		default:
+			add = append(add, strings.TrimPrefix(label, "+"))
+		}
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	if len(add) > 0 {
+		if _, resp, err := githubClient.Issues.AddLabelsToIssue(ctx, owner, repo, number, add); err != nil {
+			return "Failed to add labels: " + getActionFailReason(resp, err, "label issues", repoName, userInfo.GitHubUsername)
+		}
+	}
+
+	for _, label := range remove {
+		if resp, err := githubClient.Issues.RemoveLabelForIssue(ctx, owner, repo, number, label); err != nil {
+			return fmt.Sprintf("Failed to remove the label `%s`: %s", label, getActionFailReason(resp, err, "label issues", repoName, userInfo.GitHubUsername))
+		}
+	}
+
+	return fmt.Sprintf("You updated the labels of [%s#%d](%s%s/issues/%d).", repoName, number, p.getBaseURL(), repoName, number)
+}
+
+// issueCreateFlags are the flags of /github issue create that create the issue directly.
+var issueCreateFlags = []string{"repo", "label", "assignee", "milestone"}
+
+// getCommandBody returns the raw text of command after the first -- separator outside of quotes,
+// so that its line breaks and spacing are kept. It returns false if there is no separator.
+func getCommandBody(command string) (string, bool) {
+	inQuotes := false
+	for i, char := range command {
+		if char == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes || !unicode.IsSpace(char) {
+			continue
+		}
+
+		rest := command[i+utf8.RuneLen(char):]
+		if rest == "--" {
+			return "", true
+		}
+		if strings.HasPrefix(rest, "-- ") || strings.HasPrefix(rest, "--\n") || strings.HasPrefix(rest, "--\t") {
+			return strings.TrimSpace(rest[2:]), true
+		}
+	}
+
+	return "", false
+}
+
+// handleIssueCreate opens the create issue modal in the webapp. With any of issueCreateFlags given, the issue
+// is created directly instead, e.g. /github issue create --repo owner/repo --label bug "Title" -- body.
+func (p *Plugin) handleIssueCreate(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	hasFlag := false
+	for _, parameter := range parameters {
+		if parameter == "--" {
+			break
+		}
+		if isFlag(parameter) && containsValue(issueCreateFlags, parseFlag(parameter)) {
+			hasFlag = true
+		}
+	}
+	if !hasFlag {
+		p.openIssueCreateModal(args.UserId, args.ChannelId, strings.Join(parameters, " "))
+		return ""
+	}
+
+	usage := "Please specify a title, e.g. `/github issue create --repo owner/repo [--label bug] [--assignee @me] [--milestone \"v2\"] \"Title\" [-- body]`."
+	issue := &IssueRequest{ChannelID: args.ChannelId}
+	milestone := ""
+	var title []string
+	for i := 0; i < len(parameters); i++ {
+		parameter := parameters[i]
+		if parameter == "--" {
+			issue.Body, _ = getCommandBody(args.Command)
+			break
+		}
+		if !isFlag(parameter) {
+			title = append(title, trimQuotes(parameter))
+			continue
+		}
+
+		flag := parseFlag(parameter)
+		if i+1 >= len(parameters) {
+			return fmt.Sprintf("Please provide a value for --%s.", flag)
+		}
+		i++
+		value := trimQuotes(parameters[i])
+
+		switch flag {
+		case "repo":
+			issue.Repo = value
+		case "label":
+			issue.Labels = append(issue.Labels, value)
+		case "assignee":
+			login := userInfo.GitHubUsername
+			if value != "@me" {
+				var err error
+				if login, err = p.resolveGitHubLogin(value); err != nil {
+					return err.Error()
+				}
+			}
+			issue.Assignees = append(issue.Assignees, login)
+		case "milestone":
+			milestone = value
+		default:
+			return fmt.Sprintf("Unknown flag --%s. Supported flags are --repo, --label, --assignee and --milestone.", flag)
+		}
+	}
+
+	issue.Title = strings.Join(title, " ")
+	if issue.Title == "" {
+		return usage
+	}
+
+	owner, repo := parseOwnerAndRepo(issue.Repo, p.getBaseURL())
+	if issue.Repo == "" {
+		owner, repo = p.getChannelDefaultRepo(args.ChannelId)
+	}
+	if repo == "" {
+		return "Please specify a repository with --repo owner/repo."
+	}
+	issue.Repo = fullNameFromOwnerAndRepo(owner, repo)
+
+	ctx := context.Background()
+	if milestone != "" {
+		githubClient := p.githubConnectUser(ctx, userInfo)
+		milestones, err := listMilestones(ctx, githubClient, owner, repo)
+		if err != nil {
+			p.API.LogWarn("Failed to list milestones", "repo", issue.Repo, "error", err.Error())
+			return "Failed to get the milestones of " + issue.Repo + "."
+		}
+		for _, m := range milestones {
+			if strings.EqualFold(m.GetTitle(), milestone) {
+				issue.Milestone = m.GetNumber()
+			}
+		}
+		if issue.Milestone == 0 {
+			return fmt.Sprintf("There is no open milestone %q in %s.", milestone, issue.Repo)
+		}
+	}
+
+	// The confirmation is posted by createGitHubIssue, the same way as for issues created in the webapp.
+	if _, apiErr := p.createGitHubIssue(ctx, args.UserId, userInfo, issue); apiErr != nil {
+		return "Failed to create the issue: " + strings.TrimPrefix(apiErr.Message, "failed to create issue: ")
+	}
+
+	return ""
+}
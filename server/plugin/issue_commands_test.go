@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseIssueCommandRef(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "subscribed", Repository: "mattermost/mattermost-server"},
+	})
+	p.setConfiguration(&Configuration{})
+	require.NoError(t, p.setChannelDefaultRepo("default", "mattermost/mattermost-server"))
+
+	owner, repo, number, err := p.parseIssueCommandRef("default", "#12")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"mattermost", "mattermost-server", 12}, []interface{}{owner, repo, number})
+
+	owner, repo, number, err = p.parseIssueCommandRef("subscribed", "mattermost/mattermost-webapp#3")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"mattermost", "mattermost-webapp", 3}, []interface{}{owner, repo, number})
+
+	_, _, _, err = p.parseIssueCommandRef("subscribed", "#12")
+	assert.Error(t, err, "subscriptions don't make a default repository")
+	_, _, _, err = p.parseIssueCommandRef("default", "#abc")
+	assert.Error(t, err)
+}
+
+func TestIssueCommands(t *testing.T) {
+	var requests []string
+	var received []map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received = append(received, body)
+		if strings.HasPrefix(r.URL.Path, "/api/v3/repos/mattermost/mattermost-server/issues/404/") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/v3/repos/mattermost/mattermost-server/issues/403/") {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Must have admin rights to Repository."}`)
+			return
+		}
+		if r.URL.Path == "/api/v3/repos/mattermost/mattermost-server/issues/1/labels" && r.Method == http.MethodPost {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `{"html_url": "https://github.com/mattermost/mattermost-server/issues/1#issuecomment-1"}`)
+	})
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+	userInfo := serveTestGitHub(t, p, nil, mux)
+	require.NoError(t, p.storeGitHubUserInfo(&GitHubUserInfo{UserID: "alice", GitHubUsername: "alice-gh", Token: &oauth2.Token{AccessToken: "token"}}))
+	api.On("GetUserByUsername", "alice").Return(&model.User{Id: "alice"}, nil)
+
+	run := func(parameters ...string) string {
+		requests, received = nil, nil
+		return p.handleIssue(nil, &model.CommandArgs{UserId: "user", ChannelId: "channel"}, parameters, userInfo)
+	}
+
+	t.Run("close with reason", func(t *testing.T) {
+		msg := run("close", "mattermost/mattermost-server#1", "Duplicate", "of", "#2")
+		assert.Contains(t, msg, "You closed [mattermost/mattermost-server#1]")
+		assert.Equal(t, []string{"POST /api/v3/repos/mattermost/mattermost-server/issues/1/comments", "PATCH /api/v3/repos/mattermost/mattermost-server/issues/1"}, requests)
+		assert.Equal(t, "Duplicate of #2", received[0]["body"])
+		assert.Equal(t, "closed", received[1]["state"])
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		run("reopen", "mattermost/mattermost-server#1")
+		assert.Equal(t, []string{"PATCH /api/v3/repos/mattermost/mattermost-server/issues/1"}, requests)
+		assert.Equal(t, "open", received[0]["state"])
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		msg := run("comment", "mattermost/mattermost-server#1", "Fixed")
+		assert.Equal(t, "You [commented](https://github.com/mattermost/mattermost-server/issues/1#issuecomment-1) on mattermost/mattermost-server#1.", msg)
+	})
+
+	t.Run("assign", func(t *testing.T) {
+		run("assign", "mattermost/mattermost-server#1", "@alice")
+		assert.Equal(t, []interface{}{"alice-gh"}, received[0]["assignees"])
+	})
+
+	t.Run("label", func(t *testing.T) {
+		run("label", "mattermost/mattermost-server#1", "+bug", "-triage")
+		assert.Equal(t, []string{"POST /api/v3/repos/mattermost/mattermost-server/issues/1/labels", "DELETE /api/v3/repos/mattermost/mattermost-server/issues/1/labels/triage"}, requests)
+	})
+
+	t.Run("errors are explained", func(t *testing.T) {
+		msg := run("comment", "mattermost/mattermost-server#404", "Hello")
+		assert.Equal(t, "Failed to comment on the issue: "+getFailReason(http.StatusNotFound, "mattermost/mattermost-server", "octocat"), msg)
+
+		msg = run("label", "mattermost/mattermost-server#403", "bug")
+		assert.Equal(t, "Failed to add labels: Sorry, you don't have enough permissions to label issues in the repo mattermost/mattermost-server with the user octocat", msg)
+	})
+}
+
+func TestIssueCreateCommand(t *testing.T) {
+	var received map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/milestones", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"number": 7, "title": "v2"}]`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{"number": 5, "html_url": "https://github.com/mattermost/mattermost-server/issues/5"}`)
+	})
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+	userInfo := serveTestGitHub(t, p, nil, mux)
+	api.On("GetUser", "user").Return(&model.User{Id: "user", Username: "user"}, nil)
+
+	run := func(command string) string {
+		_, _, parameters := parseCommand("/github issue " + command)
+		return p.handleIssue(nil, &model.CommandArgs{UserId: "user", ChannelId: "channel", Command: "/github issue " + command}, parameters, userInfo)
+	}
+
+	t.Run("creates the issue directly", func(t *testing.T) {
+		api.On("SendEphemeralPost", "user", &model.Post{
+			Message:   "Created GitHub issue [#5](https://github.com/mattermost/mattermost-server/issues/5)",
+			ChannelId: "channel",
+			UserId:    "user",
+		}).Return(&model.Post{}).Once()
+
+		msg := run(`create --repo mattermost/mattermost-server --label bug --label triage --assignee @me --milestone "v2" "Fix the crash" -- It crashes on start`)
+		assert.Equal(t, "", msg)
+		assert.Equal(t, "Fix the crash", received["title"])
+		assert.Equal(t, "It crashes on start", received["body"])
+		assert.Equal(t, []interface{}{"bug", "triage"}, received["labels"])
+		assert.Equal(t, []interface{}{"octocat"}, received["assignees"])
+		assert.Equal(t, float64(7), received["milestone"])
+		api.AssertNumberOfCalls(t, "SendEphemeralPost", 1)
+	})
+
+	t.Run("the body keeps its line breaks and spacing", func(t *testing.T) {
+		api.On("SendEphemeralPost", "user", mock.Anything).Return(&model.Post{}).Once()
+
+		msg := run("create --repo mattermost/mattermost-server \"Fix -- the crash\" --\nSteps:\n\n    go run .\n1. It  crashes")
+		assert.Equal(t, "", msg)
+		assert.Equal(t, "Fix -- the crash", received["title"])
+		assert.Equal(t, "Steps:\n\n    go run .\n1. It  crashes", received["body"])
+	})
+
+	t.Run("unknown milestone", func(t *testing.T) {
+		msg := run(`create --repo mattermost/mattermost-server --milestone v3 Title`)
+		assert.Equal(t, `There is no open milestone "v3" in mattermost/mattermost-server.`, msg)
+	})
+
+	t.Run("requires a repository", func(t *testing.T) {
+		assert.Equal(t, "Please specify a repository with --repo owner/repo.", run("create --label bug Title"))
+	})
+
+	t.Run("without flags the dialog is opened", func(t *testing.T) {
+		api.On("PublishWebSocketEvent", wsEventCreateIssue, map[string]interface{}{"title": "Fix the crash", "channel_id": "channel"}, &model.WebsocketBroadcast{UserId: "user"}).Once()
+		assert.Equal(t, "", run("create Fix the crash"))
+		api.AssertNumberOfCalls(t, "PublishWebSocketEvent", 1)
+
+		api.On("PublishWebSocketEvent", wsEventCreateIssue, map[string]interface{}{"title": "Support --force in the CLI", "channel_id": "channel"}, &model.WebsocketBroadcast{UserId: "user"}).Once()
+		assert.Equal(t, "", run("create Support --force in the CLI"), "unknown flags are part of the title")
+		api.AssertNumberOfCalls(t, "PublishWebSocketEvent", 2)
+	})
+}
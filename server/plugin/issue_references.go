@@ -0,0 +1,234 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+const (
+	issueReferenceKey = "issueref_"
+
+	// issueReferenceCacheTTL is how long the title and state of a referenced issue are trusted.
+	issueReferenceCacheTTL = 10 * time.Minute
+
+	// maxIssueReferenceReplacements sets the maximum number of issue references
+	// that are expanded in a single message.
+	maxIssueReferenceReplacements = 10
+)
+
+// issueReferencesTimeout caps the time spent looking up the issue references of a single message.
+// References that aren't looked up in time are left as they are.
+var issueReferencesTimeout = 5 * time.Second
+
+// issueReferenceRegex matches owner/repo#123, #123 and GH-123 at the start of a message or after a space or (.
+var issueReferenceRegex = regexp.MustCompile(`(?:^|[\s(])((?:([\w.-]+)/([\w.-]+))?#([0-9]+)|GH-([0-9]+))\b`)
+
+// codeRegex matches code blocks and code spans of a message.
+var codeRegex = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+
+// codeOrLinkRegex matches the parts of a message references are not expanded in:
+// code blocks, code spans, markdown links and plain URLs.
+var codeOrLinkRegex = regexp.MustCompile(codeRegex.String() + "|\\[[^\\]\n]*\\]\\([^)\n]*\\)|https?://\\S+")
+
+// issueReference is the part of an issue or pull request that is appended to a reference.
+type issueReference struct {
+	Title string `json:"title"`
+	State string `json:"state"`
+	URL   string `json:"url"`
+}
+
+// issueReferenceReplacement is an issue reference found in a message.
+type issueReferenceReplacement struct {
+	start  int    // index of the reference in the message
+	end    int    // index after the reference
+	word   string // the reference as written
+	owner  string
+	repo   string
+	number int
+}
+
+// getIssueReferenceReplacements returns the issue references in msg that can be expanded, sorted by the index in ascending order.
+// #123 and GH-123 are only expanded if a default repository was set for the channel with /github channel set-repo.
+func (p *Plugin) getIssueReferenceReplacements(msg, channelID string) []issueReferenceReplacement {
+	skipped := codeOrLinkRegex.FindAllStringIndex(msg, -1)
+	isSkipped := func(index int) bool {
+		for _, r := range skipped {
+			if index >= r[0] && index < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var defaultOwner, defaultRepo string
+	defaultRepoLoaded := false
+
+	var replacements []issueReferenceReplacement
+	for _, m := range issueReferenceRegex.FindAllStringSubmatchIndex(msg, -1) {
+		if len(replacements) == maxIssueReferenceReplacements {
+			break
+		}
+
+		start, end := m[2], m[3]
+		if isSkipped(start) || isInsideLink(msg, start) {
+			continue
+		}
+
+		r := issueReferenceReplacement{start: start, end: end, word: msg[start:end]}
+		numberGroup := 8
+		switch {
+		case m[4] >= 0:
+			r.owner, r.repo = msg[m[4]:m[5]], msg[m[6]:m[7]]
+		default:
+			if !defaultRepoLoaded {
+				defaultOwner, defaultRepo = p.getChannelDefaultRepo(channelID)
+				defaultRepoLoaded = true
+			}
+			if defaultRepo == "" {
+				continue
+			}
+			r.owner, r.repo = defaultOwner, defaultRepo
+			if m[10] >= 0 {
+				numberGroup = 10
+			}
+		}
+
+		number, err := strconv.Atoi(msg[m[numberGroup]:m[numberGroup+1]])
+		if err != nil || number <= 0 {
+			continue
+		}
+		r.number = number
+
+		replacements = append(replacements, r)
+	}
+
+	return replacements
+}
+
+// getIssueReferenceKey returns the key of the cached issue reference of a user. Lookups are cached
+// per user, so that the title of an issue in a private repository isn't revealed to other users.
+func getIssueReferenceKey(userID, owner, repo string, number int) string {
+	ref := strings.ToLower(fmt.Sprintf("%s/%s/%s#%d", userID, owner, repo, number))
+	return fmt.Sprintf("%s%x", issueReferenceKey, sha256.Sum256([]byte(ref)))
+}
+
+// getIssueReference returns the title and state of an issue or pull request, looked up with the
+// GitHub client of the user. It returns nil if the issue can't be found or must not be expanded.
+func (p *Plugin) getIssueReference(ctx context.Context, ghClient *github.Client, userID, owner, repo string, number int, allowPrivate bool) *issueReference {
+	key := getIssueReferenceKey(userID, owner, repo, number)
+	if data, appErr := p.API.KVGet(key); appErr == nil && data != nil {
+		var ref issueReference
+		if err := json.Unmarshal(data, &ref); err == nil {
+			if ref.URL == "" {
+				return nil
+			}
+			return &ref
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	ref := p.lookupIssueReference(ctx, ghClient, owner, repo, number, allowPrivate)
+
+	// Lookups cut short by the deadline of the message are tried again with the next one.
+	if ref == nil && ctx.Err() != nil {
+		return nil
+	}
+
+	// Failed lookups are cached as well, so that every post mentioning them doesn't hit the GitHub API again.
+	cached := ref
+	if cached == nil {
+		cached = &issueReference{}
+	}
+	data, err := json.Marshal(cached)
+	if err == nil {
+		if appErr := p.API.KVSetWithExpiry(key, data, int64(issueReferenceCacheTTL/time.Second)); appErr != nil {
+			p.API.LogWarn("Failed to cache issue reference", "repo", fullNameFromOwnerAndRepo(owner, repo), "number", number, "error", appErr.Error())
+		}
+	}
+
+	return ref
+}
+
+func (p *Plugin) lookupIssueReference(ctx context.Context, ghClient *github.Client, owner, repo string, number int, allowPrivate bool) *issueReference {
+	if !allowPrivate {
+		ghRepo, _, err := ghClient.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			p.API.LogDebug("Failed to fetch repository of issue reference", "repo", fullNameFromOwnerAndRepo(owner, repo), "error", err.Error())
+			return nil
+		}
+		if ghRepo.GetPrivate() {
+			return nil
+		}
+	}
+
+	issue, _, err := ghClient.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		p.API.LogDebug("Failed to fetch issue reference", "repo", fullNameFromOwnerAndRepo(owner, repo), "number", number, "error", err.Error())
+		return nil
+	}
+
+	state := issue.GetState()
+	if issue.IsPullRequest() {
+		pr, _, err := ghClient.PullRequests.Get(ctx, owner, repo, number)
+		if err != nil {
+			p.API.LogDebug("Failed to fetch pull request reference", "repo", fullNameFromOwnerAndRepo(owner, repo), "number", number, "error", err.Error())
+		} else {
+			state = getPullRequestState(pr)
+		}
+	}
+
+	return &issueReference{
+		Title: issue.GetTitle(),
+		State: state,
+		URL:   issue.GetHTMLURL(),
+	}
+}
+
+// makeIssueReferenceReplacements links the issue references of msg and appends their title and state.
+// The references are looked up concurrently within issueReferencesTimeout.
+// The replacements slice needs to be sorted by the index in ascending order.
+func (p *Plugin) makeIssueReferenceReplacements(msg string, replacements []issueReferenceReplacement, ghClient *github.Client, userID string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), issueReferencesTimeout)
+	defer cancel()
+
+	allowPrivate := p.getConfiguration().EnableIssueReferences == "privateAndPublic"
+
+	refs := make([]*issueReference, len(replacements))
+	var wg sync.WaitGroup
+	for i, r := range replacements {
+		wg.Add(1)
+		go func(i int, r issueReferenceReplacement) {
+			defer wg.Done()
+			refs[i] = p.getIssueReference(ctx, ghClient, userID, r.owner, r.repo, r.number, allowPrivate)
+		}(i, r)
+	}
+	wg.Wait()
+
+	// iterating the slice in reverse to preserve the replacement indices.
+	for i := len(replacements) - 1; i >= 0; i-- {
+		r := replacements[i]
+
+		ref := refs[i]
+		if ref == nil {
+			continue
+		}
+
+		final := fmt.Sprintf("[%s: %s](%s) (%s)", r.word, escapeMarkdown(ref.Title), ref.URL, ref.State)
+
+		msg = msg[:r.start] + final + msg[r.end:]
+	}
+
+	return msg
+}
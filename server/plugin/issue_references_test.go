@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIssueReferenceReplacements(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "subscribed", Repository: "mattermost/mattermost-server"},
+	})
+	p.setConfiguration(&Configuration{})
+	require.NoError(t, p.setChannelDefaultRepo("default", "mattermost/mattermost-server"))
+
+	tcs := []struct {
+		name      string
+		input     string
+		channelID string
+		words     []string
+		repos     []string
+	}{
+		{
+			name:      "full reference",
+			input:     "Fixed by mattermost/mattermost-webapp#12.",
+			channelID: "other",
+			words:     []string{"mattermost/mattermost-webapp#12"},
+			repos:     []string{"mattermost/mattermost-webapp#12"},
+		}, {
+			name:      "short references use the default repository",
+			input:     "#12 and GH-13 (see #14)",
+			channelID: "default",
+			words:     []string{"#12", "GH-13", "#14"},
+			repos:     []string{"mattermost/mattermost-server#12", "mattermost/mattermost-server#13", "mattermost/mattermost-server#14"},
+		}, {
+			name:      "short references without default repository",
+			input:     "#12 and GH-13",
+			channelID: "other",
+		}, {
+			name:      "subscriptions don't make a default repository",
+			input:     "#12",
+			channelID: "subscribed",
+		}, {
+			name:      "code and links are skipped",
+			input:     "`#1` ```\n#2\n``` [#3](https://example.com) https://example.com/#4 ]( #5 foo#6 #7a",
+			channelID: "default",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			replacements := p.getIssueReferenceReplacements(tc.input, tc.channelID)
+			var words, repos []string
+			for _, r := range replacements {
+				assert.Equal(t, r.word, tc.input[r.start:r.end])
+				words = append(words, r.word)
+				repos = append(repos, fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(r.owner, r.repo), r.number))
+			}
+			assert.Equal(t, tc.words, words)
+			assert.Equal(t, tc.repos, repos)
+		})
+	}
+}
+
+func TestMakeIssueReferenceReplacements(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"private": false}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/secret", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"private": true}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues/1", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"number": 1, "title": "Crash [server] (*urgent*)", "state": "open", "html_url": "https://github.com/mattermost/mattermost-server/issues/1"}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues/2", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"number": 2, "title": "Fix crash", "state": "closed", "html_url": "https://github.com/mattermost/mattermost-server/pull/2", "pull_request": {}}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/2", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"number": 2, "state": "closed", "merged": true}`)
+	})
+
+	p := pluginWithMockedSubs(nil)
+	userInfo := serveTestGitHub(t, p, &Configuration{EnableIssueReferences: "public"}, mux)
+	ghClient := p.githubConnectUser(context.Background(), userInfo)
+
+	msg := "See mattermost/mattermost-server#1, mattermost/mattermost-server#2 and mattermost/secret#3"
+	expected := "See [mattermost/mattermost-server#1: Crash \\[server\\] \\(\\*urgent\\*\\)](https://github.com/mattermost/mattermost-server/issues/1) (open), " +
+		"[mattermost/mattermost-server#2: Fix crash](https://github.com/mattermost/mattermost-server/pull/2) (merged) and mattermost/secret#3"
+
+	assert.Equal(t, expected, p.makeIssueReferenceReplacements(msg, p.getIssueReferenceReplacements(msg, "channel"), ghClient, "user"))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&requests))
+
+	// Lookups are cached, including the ones that are not expanded.
+	assert.Equal(t, expected, p.makeIssueReferenceReplacements(msg, p.getIssueReferenceReplacements(msg, "channel"), ghClient, "user"))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&requests))
+}
+
+func TestMakeIssueReferenceReplacementsDeadline(t *testing.T) {
+	defer func(timeout time.Duration) { issueReferencesTimeout = timeout }(issueReferencesTimeout)
+	issueReferencesTimeout = 50 * time.Millisecond
+
+	var slow int32 = 1
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues/1", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"number": 1, "title": "Crash", "state": "open", "html_url": "https://github.com/mattermost/mattermost-server/issues/1"}`)
+	})
+
+	p := pluginWithMockedSubs(nil)
+	p.API.(*plugintest.API).On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	userInfo := serveTestGitHub(t, p, &Configuration{EnableIssueReferences: "privateAndPublic"}, mux)
+	ghClient := p.githubConnectUser(context.Background(), userInfo)
+
+	msg := "See mattermost/mattermost-server#1"
+	assert.Equal(t, msg, p.makeIssueReferenceReplacements(msg, p.getIssueReferenceReplacements(msg, "channel"), ghClient, "user"))
+
+	// References that ran out of time aren't cached as missing.
+	atomic.StoreInt32(&slow, 0)
+	assert.Equal(t, "See [mattermost/mattermost-server#1: Crash](https://github.com/mattermost/mattermost-server/issues/1) (open)",
+		p.makeIssueReferenceReplacements(msg, p.getIssueReferenceReplacements(msg, "channel"), ghClient, "user"))
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+// mockKVStore backs the KV methods of api with an in-memory map. Expiry is ignored.
+// The methods may be called concurrently, the returned map must only be used after they return.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	store := map[string][]byte{}
+	var lock sync.Mutex
+
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte {
+			lock.Lock()
+			defer lock.Unlock()
+			return store[key]
+		},
+		nil,
+	)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(
+		func(key string, value []byte) *model.AppError {
+			lock.Lock()
+			defer lock.Unlock()
+			store[key] = value
+			return nil
+		},
+	)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(
+		func(key string, value []byte, _ int64) *model.AppError {
+			lock.Lock()
+			defer lock.Unlock()
+			store[key] = value
+			return nil
+		},
+	)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			if options.Atomic && !bytes.Equal(store[key], options.OldValue) {
+				return false
+			}
+			if value == nil {
+				delete(store, key)
+			} else {
+				store[key] = value
+			}
+			return true
+		},
+		nil,
+	)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(
+		func(key string) *model.AppError {
+			lock.Lock()
+			defer lock.Unlock()
+			delete(store, key)
+			return nil
+		},
+	)
+
+	return store
+}
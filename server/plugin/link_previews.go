@@ -0,0 +1,335 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// maxLinkPreviews sets the maximum number of links that are previewed in a single message.
+const maxLinkPreviews = 5
+
+// linkPreviewsTimeout caps the time spent looking up the link previews of a single message.
+const linkPreviewsTimeout = 5 * time.Second
+
+const (
+	linkKindIssue   = "issues"
+	linkKindPull    = "pull"
+	linkKindCommit  = "commit"
+	linkKindCompare = "compare"
+
+	ciStatusSuccess = "success"
+	ciStatusPending = "pending"
+	ciStatusFailure = "failure"
+)
+
+// linkPreview holds the necessary info to preview a link to an issue, pull request, commit or comparison.
+type linkPreview struct {
+	url          string
+	isEnterprise bool // whether the link points to the GitHub Enterprise server
+	owner        string
+	repo         string
+	kind         string // one of issues, pull, commit or compare
+	ref          string // the number, commit SHA or compared range
+}
+
+// getLinkPreviews returns the links of msg that can be previewed, in the order they appear.
+// Links inside code are ignored, and every link is previewed once.
+func (p *Plugin) getLinkPreviews(msg string) []linkPreview {
+	code := codeRegex.FindAllStringIndex(msg, -1)
+	isCode := func(index int) bool {
+		for _, r := range code {
+			if index >= r[0] && index < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var previews []linkPreview
+	seen := map[string]bool{}
+	_, linkRegex := p.getGitHubRegexes()
+	for _, m := range linkRegex.FindAllStringSubmatchIndex(msg, -1) {
+		if len(previews) == maxLinkPreviews {
+			break
+		}
+		if isCode(m[0]) {
+			continue
+		}
+
+		preview := linkPreview{url: msg[m[0]:m[1]]}
+		for j, name := range linkRegex.SubexpNames() {
+			if j == 0 || m[2*j] < 0 {
+				continue
+			}
+			value := msg[m[2*j]:m[2*j+1]]
+			switch name {
+			case "host":
+				preview.isEnterprise = !strings.EqualFold(value, githubHost)
+			case "user":
+				preview.owner = value
+			case "repo":
+				preview.repo = value
+			case "kind":
+				preview.kind = value
+			case "ref":
+				preview.ref = value
+			}
+		}
+
+		// Trailing punctuation ends a sentence rather than the link, and issue, pull request
+		// and commit links may point to a tab like /files.
+		preview.ref = strings.TrimRight(preview.ref, ".,:;!")
+		if preview.kind != linkKindCompare {
+			preview.ref = strings.Split(preview.ref, "/")[0]
+		}
+		if preview.ref == "" {
+			continue
+		}
+
+		key := strings.ToLower(fmt.Sprintf("%s/%s/%s/%s", preview.owner, preview.repo, preview.kind, preview.ref))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		previews = append(previews, preview)
+	}
+
+	return previews
+}
+
+// makeLinkPreviewAttachments returns an attachment for each of the given links, looked up concurrently with
+// the GitHub client of the posting user within linkPreviewsTimeout. Links the user can't access are skipped.
+func (p *Plugin) makeLinkPreviewAttachments(previews []linkPreview, ghClient *github.Client) []*model.SlackAttachment {
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewsTimeout)
+	defer cancel()
+
+	allowPrivate := p.getConfiguration().EnableLinkPreview == "privateAndPublic"
+
+	results := make([]*model.SlackAttachment, len(previews))
+	var wg sync.WaitGroup
+	for i, preview := range previews {
+		wg.Add(1)
+		go func(i int, preview linkPreview, client *github.Client) {
+			defer wg.Done()
+			results[i] = p.makeLinkPreviewAttachment(ctx, client, preview, allowPrivate)
+		}(i, preview, p.getPreviewClient(preview.isEnterprise, ghClient))
+	}
+	wg.Wait()
+
+	var attachments []*model.SlackAttachment
+	for _, attachment := range results {
+		if attachment != nil {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments
+}
+
+func (p *Plugin) makeLinkPreviewAttachment(ctx context.Context, client *github.Client, preview linkPreview, allowPrivate bool) *model.SlackAttachment {
+	// Check if repo is public
+	if !allowPrivate {
+		repo, _, err := client.Repositories.Get(ctx, preview.owner, preview.repo)
+		if err != nil {
+			p.API.LogDebug("Error while fetching repository information", "error", err.Error(), "repo", preview.repo, "user", preview.owner)
+			return nil
+		}
+		if repo.GetPrivate() {
+			return nil
+		}
+	}
+
+	switch preview.kind {
+	case linkKindIssue, linkKindPull:
+		return p.getIssueLinkPreview(ctx, client, preview)
+	case linkKindCommit:
+		return p.getCommitLinkPreview(ctx, client, preview)
+	case linkKindCompare:
+		return p.getCompareLinkPreview(ctx, client, preview)
+	}
+
+	return nil
+}
+
+// getIssueLinkPreview returns the preview of an issue or pull request. GitHub redirects links
+// to issues that are pull requests, so both kinds of links are looked up as issues first.
+func (p *Plugin) getIssueLinkPreview(ctx context.Context, ghClient *github.Client, preview linkPreview) *model.SlackAttachment {
+	number, err := strconv.Atoi(preview.ref)
+	if err != nil {
+		return nil
+	}
+
+	repo := &github.Repository{FullName: github.String(fullNameFromOwnerAndRepo(preview.owner, preview.repo))}
+
+	issue, _, err := ghClient.Issues.Get(ctx, preview.owner, preview.repo, number)
+	if err != nil {
+		p.API.LogDebug("Error while fetching issue", "error", err.Error(), "repo", repo.GetFullName(), "number", number)
+		return nil
+	}
+
+	if !issue.IsPullRequest() {
+		attachment := newIssueAttachment(repo, issue, issue.GetUser(), "")
+		attachment.Fallback = attachment.Title
+		attachment.Fields = append([]*model.SlackAttachmentField{{Title: "State", Value: issue.GetState(), Short: true}}, attachment.Fields...)
+		return attachment
+	}
+
+	pr, _, err := ghClient.PullRequests.Get(ctx, preview.owner, preview.repo, number)
+	if err != nil {
+		p.API.LogDebug("Error while fetching pull request", "error", err.Error(), "repo", repo.GetFullName(), "number", number)
+		return nil
+	}
+
+	attachment := newPullRequestAttachment(repo, pr, pr.GetUser(), "")
+	attachment.Fallback = attachment.Title
+	fields := []*model.SlackAttachmentField{
+		{Title: "State", Value: getPullRequestState(pr), Short: true},
+		{Title: "Changes", Value: getDiffStat(pr.GetCommits(), pr.GetChangedFiles(), pr.GetAdditions(), pr.GetDeletions()), Short: true},
+	}
+	if status := p.getCIStatus(ctx, ghClient, preview.owner, preview.repo, pr.GetHead().GetSHA()); status != "" {
+		fields = append(fields, &model.SlackAttachmentField{Title: "CI", Value: status, Short: true})
+	}
+	attachment.Fields = append(fields, attachment.Fields...)
+
+	return attachment
+}
+
+func (p *Plugin) getCommitLinkPreview(ctx context.Context, ghClient *github.Client, preview linkPreview) *model.SlackAttachment {
+	commit, _, err := ghClient.Repositories.GetCommit(ctx, preview.owner, preview.repo, preview.ref, nil)
+	if err != nil {
+		p.API.LogDebug("Error while fetching commit", "error", err.Error(), "repo", preview.repo, "sha", preview.ref)
+		return nil
+	}
+
+	message := commit.GetCommit().GetMessage()
+	title := strings.SplitN(message, "\n", 2)[0]
+	sha := commit.GetSHA()
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+
+	attachment := newEventAttachment(commit.GetAuthor(), "")
+	if commit.Author == nil {
+		attachment.AuthorName = commit.GetCommit().GetAuthor().GetName()
+	}
+	attachment.Title = fmt.Sprintf("%s@%s %s", fullNameFromOwnerAndRepo(preview.owner, preview.repo), sha, title)
+	attachment.TitleLink = commit.GetHTMLURL()
+	attachment.Fallback = attachment.Title
+	attachment.Fields = []*model.SlackAttachmentField{
+		{Title: "Changes", Value: getDiffStat(0, len(commit.Files), commit.GetStats().GetAdditions(), commit.GetStats().GetDeletions()), Short: true},
+	}
+	if status := p.getCIStatus(ctx, ghClient, preview.owner, preview.repo, commit.GetSHA()); status != "" {
+		attachment.Fields = append(attachment.Fields, &model.SlackAttachmentField{Title: "CI", Value: status, Short: true})
+	}
+
+	return attachment
+}
+
+func (p *Plugin) getCompareLinkPreview(ctx context.Context, ghClient *github.Client, preview linkPreview) *model.SlackAttachment {
+	separator := "..."
+	if !strings.Contains(preview.ref, separator) {
+		separator = ".."
+	}
+	refs := strings.SplitN(preview.ref, separator, 2)
+	if len(refs) != 2 || refs[0] == "" || refs[1] == "" {
+		return nil
+	}
+	base, head := refs[0], refs[1]
+
+	comparison, _, err := ghClient.Repositories.CompareCommits(ctx, preview.owner, preview.repo, base, head, nil)
+	if err != nil {
+		p.API.LogDebug("Error while comparing commits", "error", err.Error(), "repo", preview.repo, "base", base, "head", head)
+		return nil
+	}
+
+	additions, deletions := 0, 0
+	for _, file := range comparison.Files {
+		additions += file.GetAdditions()
+		deletions += file.GetDeletions()
+	}
+
+	attachment := &model.SlackAttachment{
+		Color:     attachmentColorNeutral,
+		Title:     fmt.Sprintf("%s %s...%s", fullNameFromOwnerAndRepo(preview.owner, preview.repo), base, head),
+		TitleLink: comparison.GetHTMLURL(),
+		Fields: []*model.SlackAttachmentField{
+			{Title: "Status", Value: fmt.Sprintf("%d ahead, %d behind", comparison.GetAheadBy(), comparison.GetBehindBy()), Short: true},
+			{Title: "Changes", Value: getDiffStat(comparison.GetTotalCommits(), len(comparison.Files), additions, deletions), Short: true},
+		},
+	}
+	attachment.Fallback = attachment.Title
+	if status := p.getCIStatus(ctx, ghClient, preview.owner, preview.repo, head); status != "" {
+		attachment.Fields = append(attachment.Fields, &model.SlackAttachmentField{Title: "CI", Value: status, Short: true})
+	}
+
+	return attachment
+}
+
+// getDiffStat summarizes the size of a change. Commits are omitted if zero.
+func getDiffStat(commits, files, additions, deletions int) string {
+	stat := fmt.Sprintf("+%d −%d in %d files", additions, deletions, files)
+	if commits > 0 {
+		stat = fmt.Sprintf("%d commits, %s", commits, stat)
+	}
+
+	return stat
+}
+
+// getCIStatus returns the combined state of the commit statuses and check runs of ref:
+// failure if any of them failed, pending if any of them hasn't finished, and success otherwise.
+// It returns an empty string if there are none.
+func (p *Plugin) getCIStatus(ctx context.Context, ghClient *github.Client, owner, repo, ref string) string {
+	var states []string
+
+	combined, _, err := ghClient.Repositories.GetCombinedStatus(ctx, owner, repo, ref, nil)
+	if err != nil {
+		p.API.LogDebug("Failed to fetch combined status", "error", err.Error(), "repo", repo, "ref", ref)
+	} else if combined.GetTotalCount() > 0 {
+		switch combined.GetState() {
+		case "success":
+			states = append(states, ciStatusSuccess)
+		case "pending":
+			states = append(states, ciStatusPending)
+		default:
+			states = append(states, ciStatusFailure)
+		}
+	}
+
+	checkRuns, _, err := ghClient.Checks.ListCheckRunsForRef(ctx, owner, repo, ref, &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+	if err != nil {
+		p.API.LogDebug("Failed to fetch check runs", "error", err.Error(), "repo", repo, "ref", ref)
+	} else {
+		for _, run := range checkRuns.CheckRuns {
+			switch {
+			case run.GetStatus() != "completed":
+				states = append(states, ciStatusPending)
+			case run.GetConclusion() == "success" || run.GetConclusion() == "neutral" || run.GetConclusion() == "skipped":
+				states = append(states, ciStatusSuccess)
+			default:
+				states = append(states, ciStatusFailure)
+			}
+		}
+	}
+
+	status := ""
+	for _, state := range states {
+		switch {
+		case state == ciStatusFailure:
+			return ciStatusFailure
+		case state == ciStatusPending:
+			status = ciStatusPending
+		case status == "":
+			status = ciStatusSuccess
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLinkPreviews(t *testing.T) {
+	p := NewPlugin()
+
+	tcs := []struct {
+		name  string
+		input string
+		refs  []string
+	}{
+		{
+			name:  "issue and pull request",
+			input: "See https://github.com/mattermost/mattermost-server/issues/12 and https://github.com/mattermost/mattermost-server/pull/42/files.",
+			refs:  []string{"mattermost/mattermost-server/issues/12", "mattermost/mattermost-server/pull/42"},
+		}, {
+			name:  "commit and compare",
+			input: "https://github.com/mattermost/mattermost-server/commit/cbb25838a61872b624ac512556d7bc932486a64c (https://github.com/mattermost/mattermost-server/compare/master...feature/x)",
+			refs:  []string{"mattermost/mattermost-server/commit/cbb25838a61872b624ac512556d7bc932486a64c", "mattermost/mattermost-server/compare/master...feature/x"},
+		}, {
+			name:  "duplicates and code are skipped",
+			input: "https://github.com/mattermost/mattermost-server/pull/42 https://github.com/mattermost/mattermost-server/pull/42#issuecomment-1 `https://github.com/mattermost/mattermost-server/pull/43`",
+			refs:  []string{"mattermost/mattermost-server/pull/42"},
+		}, {
+			name:  "other links",
+			input: "https://github.com/mattermost/mattermost-server/blob/master/README.md https://github.com/mattermost/mattermost-server/pulls",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var refs []string
+			for _, preview := range p.getLinkPreviews(tc.input) {
+				refs = append(refs, fmt.Sprintf("%s/%s/%s/%s", preview.owner, preview.repo, preview.kind, preview.ref))
+			}
+			assert.Equal(t, tc.refs, refs)
+		})
+	}
+}
+
+func TestMakeLinkPreviewAttachments(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"private": false}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/secret", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"private": true}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues/42", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 42, "pull_request": {}}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/42", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 42, "title": "Fix crash", "state": "open", "html_url": "https://github.com/mattermost/mattermost-server/pull/42",
+			"user": {"login": "octocat"}, "labels": [{"name": "bug"}], "head": {"sha": "abc"},
+			"commits": 2, "changed_files": 3, "additions": 10, "deletions": 4}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number": 7, "title": "Crash on start", "state": "open", "html_url": "https://github.com/mattermost/mattermost-server/issues/7", "user": {"login": "octocat"}}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/commits/abc/status", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"state": "success", "total_count": 1}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/commits/abc/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count": 2, "check_runs": [{"status": "completed", "conclusion": "success"}, {"status": "in_progress"}]}`)
+	})
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	userInfo := serveTestGitHub(t, p, &Configuration{EnableLinkPreview: "public"}, mux)
+	ghClient := p.githubConnectUser(context.Background(), userInfo)
+
+	attachments := p.makeLinkPreviewAttachments([]linkPreview{
+		{isEnterprise: true, owner: "mattermost", repo: "mattermost-server", kind: linkKindIssue, ref: "42"},
+		{isEnterprise: true, owner: "mattermost", repo: "secret", kind: linkKindPull, ref: "1"},
+		{isEnterprise: true, owner: "mattermost", repo: "mattermost-server", kind: linkKindIssue, ref: "7"},
+	}, ghClient)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "mattermost/mattermost-server#7 Crash on start", attachments[1].Title, "attachments keep the order of the links")
+
+	attachment := attachments[0]
+	assert.Equal(t, "mattermost/mattermost-server#42 Fix crash", attachment.Title)
+	assert.Equal(t, "https://github.com/mattermost/mattermost-server/pull/42", attachment.TitleLink)
+	assert.Equal(t, "octocat", attachment.AuthorName)
+	assert.Equal(t, attachmentColorOpen, attachment.Color)
+	assert.Equal(t, []*model.SlackAttachmentField{
+		{Title: "State", Value: "open", Short: true},
+		{Title: "Changes", Value: "2 commits, +10 −4 in 3 files", Short: true},
+		{Title: "CI", Value: ciStatusPending, Short: true},
+		{Title: "Labels", Value: "`bug`", Short: true},
+	}, attachment.Fields)
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+)
+
+const (
+	orgMemberKey = "orgmember_"
+
+	// orgMembershipCacheTTL is how long an organization membership lookup is trusted.
+	// Membership changes reported by webhook events are applied immediately.
+	orgMembershipCacheTTL = time.Hour
+
+	actionMemberAdded   = "member_added"
+	actionMemberRemoved = "member_removed"
+)
+
+// getOrgMemberKey returns the key of the cached membership of login in organization, as seen by the
+// Mattermost user viewerID. Whether private members are reported depends on who asks, so lookups are
+// cached per user. Memberships reported by organization events are true for everyone and have no viewer.
+// Both names are case insensitive on GitHub.
+func getOrgMemberKey(viewerID, organization, login string) string {
+	ref := strings.ToLower(organization + "/" + login)
+	if viewerID != "" {
+		ref = viewerID + "/" + ref
+	}
+	return fmt.Sprintf("%s%x", orgMemberKey, sha256.Sum256([]byte(ref)))
+}
+
+// getCachedOrgMembership returns whether login is a member of organization as seen by viewerID, and whether
+// the answer was cached. Memberships reported by organization events take precedence over looked up ones.
+func (p *Plugin) getCachedOrgMembership(viewerID, organization, login string) (isMember, ok bool) {
+	for _, key := range []string{getOrgMemberKey("", organization, login), getOrgMemberKey(viewerID, organization, login)} {
+		data, appErr := p.API.KVGet(key)
+		if appErr == nil && data != nil {
+			return string(data) == "1", true
+		}
+	}
+
+	return false, false
+}
+
+// cacheOrgMembership remembers whether login is a member of organization as seen by viewerID.
+// An empty viewerID caches a membership reported by an organization event.
+func (p *Plugin) cacheOrgMembership(viewerID, organization, login string, isMember bool) {
+	value := "0"
+	if isMember {
+		value = "1"
+	}
+
+	if appErr := p.API.KVSetWithExpiry(getOrgMemberKey(viewerID, organization, login), []byte(value), int64(orgMembershipCacheTTL/time.Second)); appErr != nil {
+		p.API.LogWarn("Failed to cache organization membership", "organization", organization, "GitHub username", login, "error", appErr.Error())
+	}
+}
+
+// isUserOrganizationMember reports whether user is a member of organization, looked up with the
+// GitHub client of the Mattermost user viewerID. Lookups are cached, so that events from busy
+// repositories don't exhaust the rate limit of githubClient.
+func (p *Plugin) isUserOrganizationMember(githubClient *github.Client, viewerID string, user *github.User, organization string) bool {
+	if organization == "" {
+		return false
+	}
+
+	if isMember, ok := p.getCachedOrgMembership(viewerID, organization, user.GetLogin()); ok {
+		return isMember
+	}
+
+	isMember, _, err := githubClient.Organizations.IsMember(context.Background(), organization, user.GetLogin())
+	if err != nil {
+		p.API.LogWarn("Failled to check if user is org member", "GitHub username", user.GetLogin(), "error", err.Error())
+		return false
+	}
+
+	p.cacheOrgMembership(viewerID, organization, user.GetLogin(), isMember)
+
+	return isMember
+}
+
+// handleOrganizationEvent keeps the organization membership cache up to date. Organization events
+// report private memberships as well, so they apply to every user. They outlive the memberships
+// cached before them, since all cached memberships expire after the same time.
+func (p *Plugin) handleOrganizationEvent(event *github.OrganizationEvent) {
+	organization := event.GetOrganization().GetLogin()
+	login := event.GetMembership().GetUser().GetLogin()
+	if organization == "" || login == "" {
+		return
+	}
+
+	switch event.GetAction() {
+	case actionMemberAdded:
+		p.cacheOrgMembership("", organization, login, true)
+	case actionMemberRemoved:
+		p.cacheOrgMembership("", organization, login, false)
+	}
+}
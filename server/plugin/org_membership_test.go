@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleOrganizationEvent(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+
+	newEvent := func(action string) *github.OrganizationEvent {
+		return &github.OrganizationEvent{
+			Action:       sToP(action),
+			Organization: &github.Organization{Login: sToP("Mattermost")},
+			Membership:   &github.Membership{User: &github.User{Login: sToP("panda")}},
+		}
+	}
+
+	_, ok := p.getCachedOrgMembership("user1", "mattermost", "panda")
+	assert.False(t, ok)
+
+	t.Run("lookups are cached per user", func(t *testing.T) {
+		p.cacheOrgMembership("user1", "mattermost", "panda", true)
+
+		isMember, ok := p.getCachedOrgMembership("user1", "Mattermost", "Panda")
+		assert.True(t, ok)
+		assert.True(t, isMember)
+
+		_, ok = p.getCachedOrgMembership("user2", "mattermost", "panda")
+		assert.False(t, ok)
+	})
+
+	t.Run("events apply to every user", func(t *testing.T) {
+		p.handleOrganizationEvent(newEvent(actionMemberRemoved))
+		isMember, ok := p.getCachedOrgMembership("user1", "mattermost", "panda")
+		assert.True(t, ok)
+		assert.False(t, isMember)
+
+		p.handleOrganizationEvent(newEvent(actionMemberAdded))
+		isMember, ok = p.getCachedOrgMembership("user2", "mattermost", "Panda")
+		assert.True(t, ok)
+		assert.True(t, isMember)
+	})
+}
@@ -3,6 +3,8 @@ package plugin
 import (
 	"context"
 	"encoding/hex"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -23,11 +25,73 @@ const maxPreviewLines = 10
 // if the link points to a single line.
 const permalinkLineContext = 3
 
+const githubHost = "github.com"
+
+// getEnterpriseHost returns the host and path prefix of the configured GitHub Enterprise server,
+// e.g. github.example.com, or an empty string if the plugin connects to github.com.
+func getEnterpriseHost(config *Configuration) string {
+	// Same as getGitHubClient, which only connects to GitHub Enterprise if both URLs are set.
+	if config.EnterpriseBaseURL == "" || config.EnterpriseUploadURL == "" {
+		return ""
+	}
+
+	baseURL, err := url.Parse(config.EnterpriseBaseURL)
+	if err != nil || baseURL.Host == "" {
+		return ""
+	}
+
+	return strings.ToLower(baseURL.Host + strings.TrimSuffix(baseURL.Path, "/"))
+}
+
+// getGitHubHostPattern returns the pattern matching the hosts links are recognized for: github.com,
+// and the GitHub Enterprise server if one is configured. The host is captured in the host group.
+func getGitHubHostPattern(config *Configuration) string {
+	hosts := regexp.QuoteMeta(githubHost)
+	if enterpriseHost := getEnterpriseHost(config); enterpriseHost != "" {
+		hosts += "|" + regexp.QuoteMeta(enterpriseHost)
+	}
+
+	return `(?P<host>` + hosts + `)`
+}
+
+// setGitHubRegexes builds the regular expressions that recognize links in post messages for the given configuration.
+func (p *Plugin) setGitHubRegexes(config *Configuration) {
+	hostPattern := getGitHubHostPattern(config)
+	permalinkRegex := regexp.MustCompile(`https?://(?P<haswww>www\.)?` + hostPattern + `/(?P<user>[\w-]+)/(?P<repo>[\w-]+)/blob/(?P<commit>\w+)/(?P<path>[\w-/.]+)#(?P<line>[\w-]+)?`)
+	linkRegex := regexp.MustCompile(`https?://(?:www\.)?` + hostPattern + `/(?P<user>[\w.-]+)/(?P<repo>[\w.-]+)/(?P<kind>issues|pull|commit|compare)/(?P<ref>[^\s?#)>\]]+)`)
+
+	p.githubRegexLock.Lock()
+	defer p.githubRegexLock.Unlock()
+
+	p.githubPermalinkRegex = permalinkRegex
+	p.githubLinkRegex = linkRegex
+}
+
+// getGitHubRegexes returns the regular expressions that recognize permalinks and links to issues,
+// pull requests, commits and comparisons in post messages.
+func (p *Plugin) getGitHubRegexes() (permalinkRegex, linkRegex *regexp.Regexp) {
+	p.githubRegexLock.RLock()
+	defer p.githubRegexLock.RUnlock()
+
+	return p.githubPermalinkRegex, p.githubLinkRegex
+}
+
+// getPreviewClient returns the client to fetch previews of a link with. Links to github.com on plugins
+// connected to GitHub Enterprise are fetched with publicGitHubClient, so only public repositories are previewed.
+func (p *Plugin) getPreviewClient(isEnterprise bool, ghClient *github.Client) *github.Client {
+	if isEnterprise || getEnterpriseHost(p.getConfiguration()) == "" {
+		return ghClient
+	}
+
+	return p.publicGitHubClient
+}
+
 // replacement holds necessary info to replace github permalinks
 // in messages with a code preview block.
 type replacement struct {
 	index         int      // index of the permalink in the string
 	word          string   // the permalink
+	isEnterprise  bool     // whether the permalink points to the GitHub Enterprise server
 	permalinkInfo struct { // holds the necessary metadata of a permalink
 		haswww string
 		commit string
@@ -42,8 +106,9 @@ type replacement struct {
 // on a message. The returned slice is sorted by the index in ascending order.
 func (p *Plugin) getReplacements(msg string) []replacement {
 	// find the permalinks from the msg using a regex
-	matches := p.githubPermalinkRegex.FindAllStringSubmatch(msg, -1)
-	indices := p.githubPermalinkRegex.FindAllStringIndex(msg, -1)
+	permalinkRegex, _ := p.getGitHubRegexes()
+	matches := permalinkRegex.FindAllStringSubmatch(msg, -1)
+	indices := permalinkRegex.FindAllStringIndex(msg, -1)
 	var replacements []replacement
 	for i, m := range matches {
 		// have a limit on the number of replacements to do
@@ -61,11 +126,13 @@ func (p *Plugin) getReplacements(msg string) []replacement {
 			continue
 		}
 		// populate the permalinkInfo with the extracted groups of the regex
-		for j, name := range p.githubPermalinkRegex.SubexpNames() {
+		for j, name := range permalinkRegex.SubexpNames() {
 			if j == 0 {
 				continue
 			}
 			switch name {
+			case "host":
+				r.isEnterprise = !strings.EqualFold(m[j], githubHost)
 			case "haswww":
 				r.permalinkInfo.haswww = m[j]
 			case "user":
@@ -102,9 +169,11 @@ func (p *Plugin) makeReplacements(msg string, replacements []replacement, ghClie
 		ctx, cancel := context.WithTimeout(context.Background(), permalinkReqTimeout)
 		defer cancel()
 
+		client := p.getPreviewClient(r.isEnterprise, ghClient)
+
 		// Check if repo is public
 		if config.EnableCodePreview != "privateAndPublic" {
-			repo, _, err := ghClient.Repositories.Get(ctx, r.permalinkInfo.user, r.permalinkInfo.repo)
+			repo, _, err := client.Repositories.Get(ctx, r.permalinkInfo.user, r.permalinkInfo.repo)
 			if err != nil {
 				p.API.LogError("Error while fetching repository information",
 					"error", err.Error(),
@@ -123,7 +192,7 @@ func (p *Plugin) makeReplacements(msg string, replacements []replacement, ghClie
 			Ref: r.permalinkInfo.commit,
 		}
 		// TODO: make all of these requests concurrently.
-		fileContent, _, _, err := ghClient.Repositories.GetContents(ctx,
+		fileContent, _, _, err := client.Repositories.GetContents(ctx,
 			r.permalinkInfo.user, r.permalinkInfo.repo, r.permalinkInfo.path, &opts)
 		if err != nil {
 			p.API.LogError("Error while fetching file contents", "error", err.Error(), "path", r.permalinkInfo.path)
@@ -240,6 +240,40 @@ func TestGetReplacements(t *testing.T) {
 	}
 }
 
+func TestGetReplacementsEnterprise(t *testing.T) {
+	p := NewPlugin()
+	config := &Configuration{
+		EnterpriseBaseURL:   "https://github.example.com/",
+		EnterpriseUploadURL: "https://github.example.com/",
+	}
+	p.setConfiguration(config)
+
+	msg := "https://github.example.com/mattermost/mattermost-server/blob/cbb25838a61872b624ac512556d7bc932486a64c/app/authentication.go#L15-L22 " +
+		"https://github.com/mattermost/mattermost-server/blob/cbb25838a61872b624ac512556d7bc932486a64c/app/authentication.go#L15-L22"
+
+	// Before the configuration is applied, only github.com is recognized.
+	replacements := p.getReplacements(msg)
+	require.Len(t, replacements, 1)
+	assert.False(t, replacements[0].isEnterprise)
+
+	p.setGitHubRegexes(config)
+	replacements = p.getReplacements(msg)
+	require.Len(t, replacements, 2)
+	assert.True(t, replacements[0].isEnterprise)
+	assert.Equal(t, "mattermost", replacements[0].permalinkInfo.user)
+	assert.Equal(t, "app/authentication.go", replacements[0].permalinkInfo.path)
+	assert.False(t, replacements[1].isEnterprise)
+
+	ghClient := github.NewClient(nil)
+	assert.Same(t, ghClient, p.getPreviewClient(true, ghClient))
+	assert.NotSame(t, ghClient, p.getPreviewClient(false, ghClient), "github.com links are fetched without the GitHub Enterprise client")
+	assert.Same(t, p.getPreviewClient(false, ghClient), p.getPreviewClient(false, github.NewClient(nil)), "the client of github.com links is shared")
+
+	// Without an upload URL, the plugin connects to github.com.
+	p.setGitHubRegexes(&Configuration{EnterpriseBaseURL: "https://github.example.com/"})
+	assert.Len(t, p.getReplacements(msg), 1)
+}
+
 func TestMakeReplacements(t *testing.T) {
 	p := NewPlugin()
 	mockPluginAPI := &plugintest.API{}
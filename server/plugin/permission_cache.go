@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const (
+	repoPermissionsKey = "_githubrepopermissions"
+
+	// permissionCacheTTL is how long a successful permission check is trusted.
+	permissionCacheTTL = 10 * time.Minute
+	// permissionCacheNegativeTTL is how long a failed permission check is trusted.
+	// It is shorter, so that newly granted access is picked up quickly.
+	permissionCacheNegativeTTL = 2 * time.Minute
+)
+
+// cachedPermission is the result of checking whether a user can access a repository.
+type cachedPermission struct {
+	Allowed   bool  `json:"allowed"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// PermissionCacheStats counts how permission checks were answered since the plugin was activated on this node.
+type PermissionCacheStats struct {
+	Hits          int64 `json:"hits"`
+	NegativeHits  int64 `json:"negative_hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// getCachedPermissions returns the cached repository permissions of a user, keyed by repository.
+// The cache is stored in the KV store, so that it is shared across the cluster.
+func (p *Plugin) getCachedPermissions(userID string) (map[string]*cachedPermission, error) {
+	data, appErr := p.API.KVGet(userID + repoPermissionsKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get repository permissions from KVStore")
+	}
+
+	permissions := map[string]*cachedPermission{}
+	if data == nil {
+		return permissions, nil
+	}
+
+	if err := json.Unmarshal(data, &permissions); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal repository permissions")
+	}
+
+	return permissions, nil
+}
+
+// getCachedPermission returns whether userID may access repo, and whether the answer was cached.
+func (p *Plugin) getCachedPermission(userID, repo string) (allowed, ok bool) {
+	permissions, err := p.getCachedPermissions(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get cached repository permissions", "error", err.Error())
+		return false, false
+	}
+
+	permission := permissions[repo]
+	if permission == nil || permission.ExpiresAt < time.Now().Unix() {
+		atomic.AddInt64(&p.permissionCacheStats.Misses, 1)
+		return false, false
+	}
+
+	if permission.Allowed {
+		atomic.AddInt64(&p.permissionCacheStats.Hits, 1)
+	} else {
+		atomic.AddInt64(&p.permissionCacheStats.NegativeHits, 1)
+	}
+
+	return permission.Allowed, true
+}
+
+// cachePermission remembers whether userID may access repo. Expired entries of the user are dropped.
+// The permissions of a user are updated atomically, since webhook events may be delivered concurrently.
+func (p *Plugin) cachePermission(userID, repo string, allowed bool) {
+	ttl := permissionCacheTTL
+	if !allowed {
+		ttl = permissionCacheNegativeTTL
+	}
+
+	client := pluginapi.NewClient(p.API, p.Driver)
+	err := client.KV.SetAtomicWithRetries(userID+repoPermissionsKey, func(oldValue []byte) (interface{}, error) {
+		permissions := map[string]*cachedPermission{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &permissions); err != nil {
+				return nil, errors.Wrap(err, "could not unmarshal repository permissions")
+			}
+		}
+
+		now := time.Now()
+		for name, permission := range permissions {
+			if permission.ExpiresAt < now.Unix() {
+				delete(permissions, name)
+			}
+		}
+		permissions[repo] = &cachedPermission{Allowed: allowed, ExpiresAt: now.Add(ttl).Unix()}
+
+		return permissions, nil
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to store repository permissions", "error", err.Error())
+	}
+}
+
+// invalidatePermissions forgets all cached repository permissions of a user,
+// e.g. because the user connected another GitHub account.
+func (p *Plugin) invalidatePermissions(userID string) {
+	if appErr := p.API.KVDelete(userID + repoPermissionsKey); appErr != nil {
+		p.API.LogWarn("Failed to delete repository permissions", "userID", userID, "error", appErr.Error())
+		return
+	}
+
+	atomic.AddInt64(&p.permissionCacheStats.Invalidations, 1)
+}
+
+// getPermissionCacheStats returns a snapshot of the permission cache counters.
+func (p *Plugin) getPermissionCacheStats() PermissionCacheStats {
+	return PermissionCacheStats{
+		Hits:          atomic.LoadInt64(&p.permissionCacheStats.Hits),
+		NegativeHits:  atomic.LoadInt64(&p.permissionCacheStats.NegativeHits),
+		Misses:        atomic.LoadInt64(&p.permissionCacheStats.Misses),
+		Invalidations: atomic.LoadInt64(&p.permissionCacheStats.Invalidations),
+	}
+}
@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionCache(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+
+	_, ok := p.getCachedPermission("user1", "mattermost/private")
+	assert.False(t, ok)
+
+	p.cachePermission("user1", "mattermost/private", true)
+	p.cachePermission("user1", "mattermost/secret", false)
+
+	allowed, ok := p.getCachedPermission("user1", "mattermost/private")
+	assert.True(t, ok)
+	assert.True(t, allowed)
+
+	allowed, ok = p.getCachedPermission("user1", "mattermost/secret")
+	assert.True(t, ok)
+	assert.False(t, allowed)
+
+	_, ok = p.getCachedPermission("user2", "mattermost/private")
+	assert.False(t, ok)
+
+	p.invalidatePermissions("user1")
+
+	_, ok = p.getCachedPermission("user1", "mattermost/private")
+	assert.False(t, ok)
+
+	assert.Equal(t, PermissionCacheStats{Hits: 1, NegativeHits: 1, Misses: 3, Invalidations: 1}, p.getPermissionCacheStats())
+}
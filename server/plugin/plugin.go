@@ -16,6 +16,7 @@ import (
 	"github.com/google/go-github/v41/github"
 	"github.com/gorilla/mux"
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
 	"github.com/pkg/errors"
@@ -54,8 +55,13 @@ var (
 
 type Plugin struct {
 	plugin.MattermostPlugin
+	// githubRegexLock synchronizes access to the regular expressions below, which are rebuilt
+	// when the configuration changes. Consult getGitHubRegexes and setGitHubRegexes for usage.
+	githubRegexLock sync.RWMutex
 	// githubPermalinkRegex is used to parse github permalinks in post messages.
 	githubPermalinkRegex *regexp.Regexp
+	// githubLinkRegex is used to parse links to issues, pull requests, commits and comparisons in post messages.
+	githubLinkRegex *regexp.Regexp
 
 	BotUserID string
 
@@ -71,13 +77,34 @@ type Plugin struct {
 	router *mux.Router
 
 	chimeraURL string
+
+	// publicGitHubClient fetches previews of github.com links without authentication on plugins connected to
+	// GitHub Enterprise. GitHub allows 60 such requests per hour and server, so the client is shared to keep
+	// track of the rate limit and stop sending requests once it is exceeded, until it resets.
+	publicGitHubClient *github.Client
+
+	// permissionCacheStats counts the permission checks answered by the permission cache.
+	permissionCacheStats PermissionCacheStats
+
+	// subscriptionIndex holds the subscriptions in memory for webhook dispatch.
+	subscriptionIndex subscriptionIndex
+	// threadLinkIndex holds the root IDs of linked threads in memory.
+	threadLinkIndex threadLinkIndex
+
+	// subscriptionCleanupJob periodically removes and reassigns orphaned subscriptions.
+	subscriptionCleanupJob *cluster.Job
+	// subscriptionReposRefreshJob periodically resolves the repositories of topic and team subscriptions.
+	subscriptionReposRefreshJob *cluster.Job
+	// subscriptionReposRefreshes holds the refreshes of topic and team subscriptions queued by webhook events.
+	subscriptionReposRefreshes subscriptionReposRefreshes
 }
 
 // NewPlugin returns an instance of a Plugin.
 func NewPlugin() *Plugin {
 	p := &Plugin{
-		githubPermalinkRegex: regexp.MustCompile(`https?://(?P<haswww>www\.)?github\.com/(?P<user>[\w-]+)/(?P<repo>[\w-]+)/blob/(?P<commit>\w+)/(?P<path>[\w-/.]+)#(?P<line>[\w-]+)?`),
+		publicGitHubClient: github.NewClient(nil),
 	}
+	p.setGitHubRegexes(&Configuration{})
 
 	p.CommandHandlers = map[string]CommandHandleFunc{
 		"subscriptions": p.handleSubscriptions,
@@ -91,6 +118,9 @@ func NewPlugin() *Plugin {
 		"":              p.handleHelp,
 		"settings":      p.handleSettings,
 		"issue":         p.handleIssue,
+		"watch":         p.handleWatch,
+		"pr":            p.handlePullRequest,
+		"channel":       p.handleChannel,
 	}
 
 	return p
@@ -189,6 +219,18 @@ func (p *Plugin) OnActivate() error {
 		return errors.Wrap(appErr, "couldn't set profile image")
 	}
 
+	if err := p.migrateSubscriptions(); err != nil {
+		return errors.Wrap(err, "failed to migrate subscriptions")
+	}
+
+	if err := p.scheduleSubscriptionCleanup(); err != nil {
+		return err
+	}
+
+	if err := p.scheduleSubscriptionReposRefresh(); err != nil {
+		return err
+	}
+
 	registerGitHubToUsernameMappingCallback(p.getGitHubToUsernameMapping)
 
 	go func() {
@@ -200,6 +242,24 @@ func (p *Plugin) OnActivate() error {
 	return nil
 }
 
+func (p *Plugin) OnDeactivate() error {
+	p.stopSubscriptionReposRefreshes()
+
+	if p.subscriptionCleanupJob != nil {
+		if err := p.subscriptionCleanupJob.Close(); err != nil {
+			return errors.Wrap(err, "failed to close subscription cleanup job")
+		}
+	}
+
+	if p.subscriptionReposRefreshJob != nil {
+		if err := p.subscriptionReposRefreshJob.Close(); err != nil {
+			return errors.Wrap(err, "failed to close subscription repositories refresh job")
+		}
+	}
+
+	return nil
+}
+
 // registerChimeraURL fetches the Chimera URL from server settings or env var and sets it in the plugin object.
 func (p *Plugin) registerChimeraURL() {
 	chimeraURLSetting := p.API.GetConfig().PluginSettings.ChimeraOAuthProxyURL
@@ -209,12 +269,6 @@ func (p *Plugin) registerChimeraURL() {
 }
 
 func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
-	// If not enabled in config, ignore.
-	config := p.getConfiguration()
-	if config.EnableCodePreview == "disable" {
-		return nil, ""
-	}
-
 	if post.UserId == "" {
 		return nil, ""
 	}
@@ -242,8 +296,33 @@ func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*mode
 	// TODO: make this part of the Plugin struct and reuse it.
 	ghClient := p.githubConnectUser(context.Background(), info)
 
-	replacements := p.getReplacements(msg)
-	post.Message = p.makeReplacements(msg, replacements, ghClient)
+	config := p.getConfiguration()
+
+	// If not enabled in config, don't replace permalinks with code previews.
+	if config.EnableCodePreview != "disable" {
+		replacements := p.getReplacements(msg)
+		msg = p.makeReplacements(msg, replacements, ghClient)
+	}
+
+	if config.EnableIssueReferences != "disable" {
+		issueReferences := p.getIssueReferenceReplacements(msg, post.ChannelId)
+		msg = p.makeIssueReferenceReplacements(msg, issueReferences, ghClient, post.UserId)
+	}
+
+	// Links are previewed as written by the user, not the ones added for issue references.
+	var attachments []*model.SlackAttachment
+	if config.EnableLinkPreview != "disable" {
+		attachments = p.makeLinkPreviewAttachments(p.getLinkPreviews(post.Message), ghClient)
+	}
+
+	if msg == post.Message && len(attachments) == 0 {
+		return nil, ""
+	}
+
+	post.Message = msg
+	if len(attachments) > 0 {
+		model.ParseSlackAttachment(post, append(post.Attachments(), attachments...))
+	}
 	return post, ""
 }
 
@@ -396,6 +475,26 @@ func (p *Plugin) getGitHubToUsernameMapping(githubUsername string) string {
 	return user.Username
 }
 
+// resolveGitHubLogin maps a Mattermost @username to the GitHub username of its connected account.
+// Names without a leading @ are taken as GitHub usernames.
+func (p *Plugin) resolveGitHubLogin(name string) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		return name, nil
+	}
+
+	user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(name, "@"))
+	if appErr != nil {
+		return "", errors.Errorf("%s is not a Mattermost user.", name)
+	}
+
+	info, apiErr := p.getGitHubUserInfo(user.Id)
+	if apiErr != nil {
+		return "", errors.Errorf("%s has not connected their GitHub account.", name)
+	}
+
+	return info.GitHubUsername, nil
+}
+
 func (p *Plugin) disconnectGitHubAccount(userID string) {
 	userInfo, _ := p.getGitHubUserInfo(userID)
 	if userInfo == nil {
@@ -410,6 +509,8 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		p.API.LogWarn("Failed to delete github token from KV store", "userID", userID, "error", appErr.Error())
 	}
 
+	p.invalidatePermissions(userID)
+
 	user, appErr := p.API.GetUser(userID)
 	if appErr != nil {
 		p.API.LogWarn("Failed to get user props", "userID", userID, "error", appErr.Error())
@@ -698,20 +799,6 @@ func (p *Plugin) checkOrg(org string) error {
 	return nil
 }
 
-func (p *Plugin) isUserOrganizationMember(githubClient *github.Client, user *github.User, organization string) bool {
-	if organization == "" {
-		return false
-	}
-
-	isMember, _, err := githubClient.Organizations.IsMember(context.Background(), organization, *user.Login)
-	if err != nil {
-		p.API.LogWarn("Failled to check if user is org member", "GitHub username", *user.Login, "error", err.Error())
-		return false
-	}
-
-	return isMember
-}
-
 func (p *Plugin) isOrganizationLocked() bool {
 	config := p.getConfiguration()
 	configOrg := strings.TrimSpace(config.GitHubOrg)
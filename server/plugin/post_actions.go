@@ -0,0 +1,370 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	postActionAssignMe       = "assign_me"
+	postActionApprove        = "approve"
+	postActionRequestChanges = "request_changes"
+	postActionMerge          = "merge"
+	postActionClose          = "close"
+	postActionReopen         = "reopen"
+	postActionAddLabel       = "add_label"
+	postActionRemoveLabel    = "remove_label"
+	postActionSetMilestone   = "set_milestone"
+	postActionAssign         = "assign"
+
+	postActionContextAction = "action"
+	postActionContextRepo   = "repo"
+	postActionContextNumber = "number"
+	postActionContextPostID = "post_id"
+	postActionSelected      = "selected_option"
+
+	issueTriageOptionsKey = "triageoptions_"
+
+	// issueTriageOptionsCacheTTL is how long the labels, milestones and assignees of a repository are trusted.
+	issueTriageOptionsCacheTTL = 10 * time.Minute
+	// issueTriageOptionsTimeout limits how long fetching the triage options of a repository may take.
+	issueTriageOptionsTimeout = 10 * time.Second
+)
+
+// getPostActionURL returns the plugin URL post actions under the given path are sent to.
+func getPostActionURL(path string) string {
+	return fmt.Sprintf("/plugins/%s/api/v1/actions%s", Manifest.Id, path)
+}
+
+// newPostAction returns a button sending the given action for the
+// issue or pull request number of repo to the post action endpoint at path.
+func newPostAction(path, action, name, style, repo string, number int) *model.PostAction {
+	return &model.PostAction{
+		Type:  model.PostActionTypeButton,
+		Name:  name,
+		Style: style,
+		Integration: &model.PostActionIntegration{
+			URL: getPostActionURL(path),
+			Context: map[string]interface{}{
+				postActionContextAction: action,
+				postActionContextRepo:   repo,
+				postActionContextNumber: number,
+			},
+		},
+	}
+}
+
+// getPullRequestActions returns the actions shown on pull request posts.
+// Closed pull requests have no actions.
+func getPullRequestActions(repo *github.Repository, pr *github.PullRequest) []*model.PostAction {
+	if pr.GetState() == "closed" {
+		return nil
+	}
+
+	repoName := repo.GetFullName()
+	number := pr.GetNumber()
+
+	merge := newPostActionSelect("/pr", postActionMerge, "Merge", repoName, number, []*model.PostActionOptions{
+		{Text: "Create a merge commit", Value: "merge"},
+		{Text: "Squash and merge", Value: "squash"},
+		{Text: "Rebase and merge", Value: "rebase"},
+	})
+
+	return []*model.PostAction{
+		newPostAction("/pr", postActionAssignMe, "Assign to me", "default", repoName, number),
+		newPostAction("/pr", postActionApprove, "Approve", "good", repoName, number),
+		newPostAction("/pr", postActionRequestChanges, "Request changes", "warning", repoName, number),
+		merge,
+		newPostAction("/pr", postActionClose, "Close", "danger", repoName, number),
+	}
+}
+
+// issueTriageOptions are the choices offered by the triage menus of issue posts.
+type issueTriageOptions struct {
+	Labels     []*github.Label
+	Milestones []*github.Milestone
+	Assignees  []*github.User
+}
+
+// getIssueTriageOptions fetches the labels, open milestones and assignable users of a repository.
+func getIssueTriageOptions(ctx context.Context, githubClient *github.Client, owner, repo string) (*issueTriageOptions, error) {
+	labels, err := listLabels(ctx, githubClient, owner, repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list labels")
+	}
+
+	milestones, err := listMilestones(ctx, githubClient, owner, repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list milestones")
+	}
+
+	assignees, err := listAssignees(ctx, githubClient, owner, repo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list assignees")
+	}
+
+	return &issueTriageOptions{
+		Labels:     labels,
+		Milestones: milestones,
+		Assignees:  assignees,
+	}, nil
+}
+
+// getIssueTriageOptionsKey returns the key of the cached triage options of repo, as seen by a user.
+// Options are cached per user, since the labels and assignees visible to users may differ.
+func getIssueTriageOptionsKey(userID, repo string) string {
+	ref := strings.ToLower(userID + "/" + repo)
+	return fmt.Sprintf("%s%x", issueTriageOptionsKey, sha256.Sum256([]byte(ref)))
+}
+
+// getCachedIssueTriageOptions returns the triage options of repo as seen by a user, fetched
+// on behalf of the user if they aren't cached. It returns nil if the user isn't connected
+// to GitHub or the options can't be fetched.
+func (p *Plugin) getCachedIssueTriageOptions(userID string, repo *github.Repository) *issueTriageOptions {
+	key := getIssueTriageOptionsKey(userID, repo.GetFullName())
+	if data, appErr := p.API.KVGet(key); appErr == nil && data != nil {
+		var options issueTriageOptions
+		if err := json.Unmarshal(data, &options); err == nil {
+			return &options
+		}
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), issueTriageOptionsTimeout)
+	defer cancel()
+
+	options, err := getIssueTriageOptions(ctx, p.githubConnectUser(ctx, info), repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		p.API.LogWarn("Failed to fetch issue triage options", "repo", repo.GetFullName(), "error", err.Error())
+		return nil
+	}
+
+	data, err := json.Marshal(options)
+	if err == nil {
+		if appErr := p.API.KVSetWithExpiry(key, data, int64(issueTriageOptionsCacheTTL/time.Second)); appErr != nil {
+			p.API.LogWarn("Failed to cache issue triage options", "repo", repo.GetFullName(), "error", appErr.Error())
+		}
+	}
+
+	return options
+}
+
+// newPostActionSelect returns a menu sending the given action with the chosen option.
+// Menus without options are omitted, so nil is returned for them.
+func newPostActionSelect(path, action, name, repo string, number int, options []*model.PostActionOptions) *model.PostAction {
+	if len(options) == 0 {
+		return nil
+	}
+
+	postAction := newPostAction(path, action, name, "", repo, number)
+	postAction.Type = model.PostActionTypeSelect
+	postAction.Options = options
+
+	return postAction
+}
+
+// getIssueActions returns the triage actions shown on issue posts. The menus for adding
+// labels, setting a milestone and assigning users are only shown if options are known.
+func getIssueActions(repo *github.Repository, issue *github.Issue, options *issueTriageOptions) []*model.PostAction {
+	repoName := repo.GetFullName()
+	number := issue.GetNumber()
+
+	if options == nil {
+		options = &issueTriageOptions{}
+	}
+
+	hasLabel := make(map[string]bool, len(issue.Labels))
+	var removeLabelOptions []*model.PostActionOptions
+	for _, label := range issue.Labels {
+		hasLabel[label.GetName()] = true
+		removeLabelOptions = append(removeLabelOptions, &model.PostActionOptions{Text: label.GetName(), Value: label.GetName()})
+	}
+
+	var addLabelOptions []*model.PostActionOptions
+	for _, label := range options.Labels {
+		if !hasLabel[label.GetName()] {
+			addLabelOptions = append(addLabelOptions, &model.PostActionOptions{Text: label.GetName(), Value: label.GetName()})
+		}
+	}
+
+	var milestoneOptions []*model.PostActionOptions
+	for _, milestone := range options.Milestones {
+		milestoneOptions = append(milestoneOptions, &model.PostActionOptions{Text: milestone.GetTitle(), Value: strconv.Itoa(milestone.GetNumber())})
+	}
+
+	var assigneeOptions []*model.PostActionOptions
+	for _, assignee := range options.Assignees {
+		assigneeOptions = append(assigneeOptions, &model.PostActionOptions{Text: assignee.GetLogin(), Value: assignee.GetLogin()})
+	}
+
+	var actions []*model.PostAction
+	for _, action := range []*model.PostAction{
+		newPostActionSelect("/issue", postActionAddLabel, "Add label", repoName, number, addLabelOptions),
+		newPostActionSelect("/issue", postActionRemoveLabel, "Remove label", repoName, number, removeLabelOptions),
+		newPostActionSelect("/issue", postActionSetMilestone, "Set milestone", repoName, number, milestoneOptions),
+		newPostActionSelect("/issue", postActionAssign, "Assign", repoName, number, assigneeOptions),
+	} {
+		if action != nil {
+			actions = append(actions, action)
+		}
+	}
+
+	if issue.GetState() == "closed" {
+		actions = append(actions, newPostAction("/issue", postActionReopen, "Reopen", "default", repoName, number))
+	} else {
+		actions = append(actions, newPostAction("/issue", postActionClose, "Close", "danger", repoName, number))
+	}
+
+	return actions
+}
+
+// parsePostActionContext returns the repository and issue or pull request number
+// stored in the context of a post action.
+func parsePostActionContext(actionContext map[string]interface{}) (owner, repo string, number int, err error) {
+	repoName, _ := actionContext[postActionContextRepo].(string)
+	owner, repo, err = parseRepo(repoName)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	// Numbers are decoded from JSON as float64.
+	switch n := actionContext[postActionContextNumber].(type) {
+	case float64:
+		number = int(n)
+	case int:
+		number = n
+	}
+	if number <= 0 {
+		return "", "", 0, errors.New("invalid number")
+	}
+
+	return owner, repo, number, nil
+}
+
+// canUpdateActionPost reports whether userID may run the actions of post for the issue or pull
+// request number of owner/repo. The post must be one of the bot's posts in a channel the user can
+// read, with an action created for that issue or pull request, as post IDs are sent by the client.
+func (p *Plugin) canUpdateActionPost(userID string, post *model.Post, owner, repo string, number int) bool {
+	if post.UserId != p.BotUserID {
+		return false
+	}
+
+	if !p.API.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		return false
+	}
+
+	for _, attachment := range post.Attachments() {
+		for _, action := range attachment.Actions {
+			if action.Integration == nil {
+				continue
+			}
+
+			actionOwner, actionRepo, actionNumber, err := parsePostActionContext(action.Integration.Context)
+			if err == nil && actionOwner == owner && actionRepo == repo && actionNumber == number {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getActionFailReason explains why a GitHub request made on behalf of username failed. action describes
+// what was attempted, e.g. "merge pull requests", and is used to explain missing permissions.
+// Validation errors returned by GitHub, e.g. when a pull request cannot be merged, are passed through.
+func getActionFailReason(resp *github.Response, err error, action, repo, username string) string {
+	var gerr *github.ErrorResponse
+	if errors.As(err, &gerr) && gerr.Message != "" {
+		switch gerr.Response.StatusCode {
+		case http.StatusMethodNotAllowed, http.StatusConflict, http.StatusUnprocessableEntity:
+			return gerr.Message
+		}
+	}
+
+	statusCode := http.StatusInternalServerError
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+
+	if statusCode == http.StatusForbidden {
+		return fmt.Sprintf("Sorry, you don't have enough permissions to %s in the repo %s with the user %s", action, repo, username)
+	}
+
+	return getFailReason(statusCode, repo, username)
+}
+
+// refreshPullRequestPost returns a copy of post with the state, fields and actions of its
+// attachments updated to the current state of the pull request.
+func (p *Plugin) refreshPullRequestPost(ctx context.Context, githubClient *github.Client, post *model.Post, owner, repo string, number int) (*model.Post, error) {
+	pr, _, err := githubClient.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pull request")
+	}
+
+	ghRepo := pr.GetBase().GetRepo()
+	if ghRepo == nil {
+		ghRepo = &github.Repository{FullName: github.String(fullNameFromOwnerAndRepo(owner, repo))}
+	}
+
+	updated := post.Clone()
+	attachments := updated.Attachments()
+	for _, attachment := range attachments {
+		if attachment.TitleLink != "" {
+			refreshed := newPullRequestAttachment(ghRepo, pr, nil, attachment.Text)
+			attachment.Color = refreshed.Color
+			attachment.Fields = refreshed.Fields
+		}
+		if len(attachment.Actions) > 0 {
+			attachment.Actions = getPullRequestActions(ghRepo, pr)
+		}
+	}
+	model.ParseSlackAttachment(updated, attachments)
+
+	return updated, nil
+}
+
+// refreshIssuePost returns a copy of post with the state, fields and actions of its
+// attachments updated to the current state of the issue. The triage options offered by
+// the actions are taken from the cache of userID.
+func (p *Plugin) refreshIssuePost(ctx context.Context, githubClient *github.Client, userID string, post *model.Post, owner, repo string, number int) (*model.Post, error) {
+	issue, _, err := githubClient.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get issue")
+	}
+
+	ghRepo := &github.Repository{
+		FullName: github.String(fullNameFromOwnerAndRepo(owner, repo)),
+		Name:     github.String(repo),
+		Owner:    &github.User{Login: github.String(owner)},
+	}
+
+	updated := post.Clone()
+	attachments := updated.Attachments()
+	for _, attachment := range attachments {
+		if attachment.TitleLink != "" {
+			refreshed := newIssueAttachment(ghRepo, issue, nil, attachment.Text)
+			attachment.Color = refreshed.Color
+			attachment.Fields = refreshed.Fields
+		}
+		if len(attachment.Actions) > 0 {
+			attachment.Actions = getIssueActions(ghRepo, issue, p.getCachedIssueTriageOptions(userID, ghRepo))
+		}
+	}
+	model.ParseSlackAttachment(updated, attachments)
+
+	return updated, nil
+}
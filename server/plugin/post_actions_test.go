@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPullRequestActions(t *testing.T) {
+	repo := github.Repository{FullName: sToP("mattermost/mattermost-plugin-github")}
+
+	t.Run("closed pull request", func(t *testing.T) {
+		pr := github.PullRequest{State: sToP("closed")}
+		assert.Nil(t, getPullRequestActions(&repo, &pr))
+	})
+
+	t.Run("open pull request", func(t *testing.T) {
+		pr := github.PullRequest{State: sToP("open"), Number: iToP(42)}
+
+		actions := getPullRequestActions(&repo, &pr)
+
+		require.Len(t, actions, 5)
+		for _, action := range actions {
+			assert.Equal(t, "/plugins/github/api/v1/actions/pr", action.Integration.URL)
+			assert.Equal(t, "mattermost/mattermost-plugin-github", action.Integration.Context[postActionContextRepo])
+			assert.Equal(t, 42, action.Integration.Context[postActionContextNumber])
+		}
+		assert.Len(t, actions[3].Options, 3)
+	})
+}
+
+func TestGetIssueActions(t *testing.T) {
+	repo := github.Repository{FullName: sToP("mattermost/mattermost-plugin-github")}
+
+	actionNames := func(actions []*model.PostAction) []string {
+		names := make([]string, len(actions))
+		for i, action := range actions {
+			names[i] = action.Name
+		}
+		return names
+	}
+
+	t.Run("without options", func(t *testing.T) {
+		issue := github.Issue{State: sToP("open"), Number: iToP(1)}
+
+		actions := getIssueActions(&repo, &issue, nil)
+
+		assert.Equal(t, []string{"Close"}, actionNames(actions))
+	})
+
+	t.Run("closed issue", func(t *testing.T) {
+		issue := github.Issue{State: sToP("closed"), Number: iToP(1)}
+
+		actions := getIssueActions(&repo, &issue, nil)
+
+		assert.Equal(t, []string{"Reopen"}, actionNames(actions))
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		issue := github.Issue{State: sToP("open"), Number: iToP(1), Labels: labels[:1]}
+		options := &issueTriageOptions{
+			Labels:     labels,
+			Milestones: []*github.Milestone{{Title: sToP("v1.0"), Number: iToP(3)}},
+			Assignees:  []*github.User{&user},
+		}
+
+		actions := getIssueActions(&repo, &issue, options)
+
+		require.Equal(t, []string{"Add label", "Remove label", "Set milestone", "Assign", "Close"}, actionNames(actions))
+		assert.Equal(t, []*model.PostActionOptions{{Text: "Tech/Go", Value: "Tech/Go"}}, actions[0].Options)
+		assert.Equal(t, []*model.PostActionOptions{{Text: "Help Wanted", Value: "Help Wanted"}}, actions[1].Options)
+		assert.Equal(t, []*model.PostActionOptions{{Text: "v1.0", Value: "3"}}, actions[2].Options)
+		assert.Equal(t, "/plugins/github/api/v1/actions/issue", actions[4].Integration.URL)
+	})
+}
+
+func TestParsePostActionContext(t *testing.T) {
+	owner, repo, number, err := parsePostActionContext(map[string]interface{}{
+		postActionContextRepo:   "mattermost/mattermost-plugin-github",
+		postActionContextNumber: float64(42),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mattermost", owner)
+	assert.Equal(t, "mattermost-plugin-github", repo)
+	assert.Equal(t, 42, number)
+
+	_, _, _, err = parsePostActionContext(map[string]interface{}{postActionContextRepo: "mattermost"})
+	assert.Error(t, err)
+
+	_, _, _, err = parsePostActionContext(map[string]interface{}{postActionContextRepo: "mattermost/mattermost-plugin-github"})
+	assert.Error(t, err)
+}
+
+func TestCanUpdateActionPost(t *testing.T) {
+	p := NewPlugin()
+	p.BotUserID = "bot"
+	api := &plugintest.API{}
+	api.On("HasPermissionToChannel", "user", "channel", model.PermissionReadChannel).Return(true)
+	api.On("HasPermissionToChannel", "outsider", "channel", model.PermissionReadChannel).Return(false)
+	p.SetAPI(api)
+
+	newPost := func(userID string) *model.Post {
+		post := &model.Post{UserId: userID, ChannelId: "channel"}
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+			Actions: []*model.PostAction{newPostAction("/pr", postActionApprove, "Approve", "good", "mattermost/mattermost-server", 1)},
+		}})
+		return post
+	}
+
+	assert.True(t, p.canUpdateActionPost("user", newPost("bot"), "mattermost", "mattermost-server", 1))
+	assert.False(t, p.canUpdateActionPost("outsider", newPost("bot"), "mattermost", "mattermost-server", 1))
+	assert.False(t, p.canUpdateActionPost("user", newPost("user"), "mattermost", "mattermost-server", 1))
+	assert.False(t, p.canUpdateActionPost("user", newPost("bot"), "mattermost", "mattermost-server", 2))
+	assert.False(t, p.canUpdateActionPost("user", newPost("bot"), "mattermost", "mattermost-webapp", 1))
+}
+
+func TestGetCachedIssueTriageOptions(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	p.SetAPI(api)
+
+	repo := &github.Repository{FullName: sToP("mattermost/mattermost-plugin-github")}
+	options := &issueTriageOptions{Labels: labels}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	store[getIssueTriageOptionsKey("user1", repo.GetFullName())] = data
+
+	cached := p.getCachedIssueTriageOptions("user1", repo)
+	require.NotNil(t, cached)
+	assert.Equal(t, []string{"Help Wanted", "Tech/Go"}, []string{cached.Labels[0].GetName(), cached.Labels[1].GetName()})
+
+	// Options are cached per user, and users who aren't connected get none.
+	assert.Nil(t, p.getCachedIssueTriageOptions("user2", repo))
+}
@@ -0,0 +1,295 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+const (
+	prListMineFlag            = "mine"
+	prListReviewRequestedFlag = "review-requested"
+
+	// prListPageSize is the number of pull requests shown by /github pr list.
+	prListPageSize = 20
+)
+
+func (p *Plugin) handlePullRequest(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) == 0 {
+		return "Invalid pr command. Available commands are 'list', 'view', 'approve', 'merge' and 'request-review'."
+	}
+
+	command := parameters[0]
+	parameters = parameters[1:]
+
+	switch {
+	case command == "list":
+		return p.handlePullRequestList(args, parameters, userInfo)
+	case command == "view":
+		return p.handlePullRequestView(args, parameters, userInfo)
+	case command == "approve":
+		return p.handlePullRequestApprove(args, parameters, userInfo)
+	case command == "merge":
+		return p.handlePullRequestMerge(args, parameters, userInfo)
+	case command == "request-review":
+		return p.handlePullRequestRequestReview(args, parameters, userInfo)
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", command)
+	}
+}
+
+// getGitHubUserMention returns an @-mention of the Mattermost user connected to a GitHub login,
+// or a link to the GitHub profile if there is none.
+func (p *Plugin) getGitHubUserMention(login string) string {
+	if username := p.getGitHubToUsernameMapping(login); username != "" {
+		return "@" + username
+	}
+
+	return fmt.Sprintf("[%s](%s%s)", login, p.getBaseURL(), login)
+}
+
+// getPullRequestListQuery returns the search query for /github pr list. Without a repository,
+// the search is limited to the configured organization, if any.
+func getPullRequestListQuery(owner, repo, username string, mine, reviewRequested bool) string {
+	query := "is:pr is:open archived:false"
+	if repo != "" {
+		query += " repo:" + fullNameFromOwnerAndRepo(owner, repo)
+	} else if owner != "" {
+		query += " org:" + owner
+	}
+	if mine {
+		query += " author:" + username
+	}
+	if reviewRequested {
+		query += " review-requested:" + username
+	}
+
+	return query
+}
+
+func (p *Plugin) handlePullRequestList(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	owner, repo := "", ""
+	mine, reviewRequested := false, false
+	for _, parameter := range parameters {
+		switch {
+		case parameter == "--"+prListMineFlag:
+			mine = true
+		case parameter == "--"+prListReviewRequestedFlag:
+			reviewRequested = true
+		case isFlag(parameter):
+			return fmt.Sprintf("Unknown flag %s. Supported flags are --%s and --%s.", parameter, prListMineFlag, prListReviewRequestedFlag)
+		case repo != "":
+			return "Just one repository is allowed."
+		default:
+			owner, repo = parseOwnerAndRepo(parameter, p.getBaseURL())
+			if repo == "" {
+				return "Please specify a repository as owner/repo."
+			}
+		}
+	}
+
+	if repo == "" && !mine && !reviewRequested {
+		owner, repo = p.getChannelDefaultRepo(args.ChannelId)
+	}
+	if repo == "" && !mine && !reviewRequested {
+		return fmt.Sprintf("Please specify a repository, or --%s or --%s to list pull requests of all repositories.", prListMineFlag, prListReviewRequestedFlag)
+	}
+	if repo == "" {
+		owner = p.getConfiguration().GitHubOrg
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+
+	query := getPullRequestListQuery(owner, repo, userInfo.GitHubUsername, mine, reviewRequested)
+	result, resp, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: prListPageSize}})
+	if err != nil {
+		p.API.LogWarn("Failed to search pull requests", "query", query, "error", err.Error())
+		return "Failed to list pull requests: " + getActionFailReason(resp, err, "list pull requests", fullNameFromOwnerAndRepo(owner, repo), userInfo.GitHubUsername)
+	}
+
+	if len(result.Issues) == 0 {
+		return "There are no matching open pull requests."
+	}
+
+	txt := "### Open pull requests\n"
+	for _, pr := range result.Issues {
+		// The repository URL is an API URL ending in /repos/owner/repo.
+		prRepoName := pr.GetRepositoryURL()
+		if i := strings.LastIndex(prRepoName, "/repos/"); i >= 0 {
+			prRepoName = prRepoName[i+len("/repos/"):]
+		}
+		txt += fmt.Sprintf("* [%s#%d](%s) %s by %s\n", prRepoName, pr.GetNumber(), pr.GetHTMLURL(), pr.GetTitle(), p.getGitHubUserMention(pr.GetUser().GetLogin()))
+	}
+	if total := result.GetTotal(); total > len(result.Issues) {
+		txt += fmt.Sprintf("\nShowing %d of %d pull requests.", len(result.Issues), total)
+	}
+
+	return txt
+}
+
+// getPullRequestState returns the state of a pull request as shown to users: open, draft, merged or closed.
+func getPullRequestState(pr *github.PullRequest) string {
+	state := pr.GetState()
+	switch {
+	case pr.GetMerged():
+		state = "merged"
+	case pr.GetDraft() && state == "open":
+		state = "draft"
+	}
+
+	return state
+}
+
+// parsePullRequestRef parses the pull request reference of a /github pr command.
+func (p *Plugin) parsePullRequestRef(channelID string, parameters []string, usage string) (owner, repo string, number int, errMsg string) {
+	if len(parameters) == 0 {
+		return "", "", 0, usage
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(channelID, parameters[0])
+	if err != nil {
+		return "", "", 0, usage
+	}
+
+	return owner, repo, number, ""
+}
+
+func (p *Plugin) handlePullRequestView(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	owner, repo, number, errMsg := p.parsePullRequestRef(args.ChannelId, parameters, "Please specify a pull request, e.g. `/github pr view owner/repo#1`.")
+	if errMsg != "" {
+		return errMsg
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	pr, resp, err := githubClient.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return "Failed to get the pull request: " + getActionFailReason(resp, err, "view pull requests", repoName, userInfo.GitHubUsername)
+	}
+
+	state := getPullRequestState(pr)
+
+	txt := fmt.Sprintf("#### [%s](%s)\n", pr.GetTitle(), pr.GetHTMLURL())
+	txt += fmt.Sprintf("##### %s#%d\n", repoName, number)
+	txt += fmt.Sprintf("**State:** %s | **Author:** %s | `%s` ← `%s`\n", state, p.getGitHubUserMention(pr.GetUser().GetLogin()), pr.GetBase().GetRef(), pr.GetHead().GetRef())
+	txt += fmt.Sprintf("**Changes:** %d commits, %d files, +%d −%d\n", pr.GetCommits(), pr.GetChangedFiles(), pr.GetAdditions(), pr.GetDeletions())
+
+	if len(pr.RequestedReviewers) > 0 {
+		reviewers := make([]string, 0, len(pr.RequestedReviewers))
+		for _, reviewer := range pr.RequestedReviewers {
+			reviewers = append(reviewers, p.getGitHubUserMention(reviewer.GetLogin()))
+		}
+		txt += "**Requested reviewers:** " + strings.Join(reviewers, ", ") + "\n"
+	}
+
+	if len(pr.Labels) > 0 {
+		labels := make([]string, 0, len(pr.Labels))
+		for _, label := range pr.Labels {
+			labels = append(labels, "`"+label.GetName()+"`")
+		}
+		txt += "**Labels:** " + strings.Join(labels, ", ") + "\n"
+	}
+
+	if pr.GetState() == "open" && pr.Mergeable != nil {
+		if pr.GetMergeable() {
+			txt += "**Mergeable:** yes\n"
+		} else {
+			txt += "**Mergeable:** no, " + pr.GetMergeableState() + "\n"
+		}
+	}
+
+	if body := p.sanitizeDescription(pr.GetBody()); body != "" {
+		txt += "\n" + body
+	}
+
+	return txt
+}
+
+func (p *Plugin) handlePullRequestApprove(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	owner, repo, number, errMsg := p.parsePullRequestRef(args.ChannelId, parameters, "Please specify a pull request, e.g. `/github pr approve owner/repo#1 [comment]`.")
+	if errMsg != "" {
+		return errMsg
+	}
+
+	review := &github.PullRequestReviewRequest{Event: github.String("APPROVE")}
+	if comment := strings.Join(parameters[1:], " "); comment != "" {
+		review.Body = github.String(comment)
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	if _, resp, err := githubClient.PullRequests.CreateReview(ctx, owner, repo, number, review); err != nil {
+		return "Failed to approve the pull request: " + getActionFailReason(resp, err, "approve pull requests", repoName, userInfo.GitHubUsername)
+	}
+
+	return fmt.Sprintf("You approved [%s#%d](%s%s/pull/%d).", repoName, number, p.getBaseURL(), repoName, number)
+}
+
+func (p *Plugin) handlePullRequestMerge(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify a pull request, e.g. `/github pr merge owner/repo#1 [--squash|--rebase]`."
+	owner, repo, number, errMsg := p.parsePullRequestRef(args.ChannelId, parameters, usage)
+	if errMsg != "" {
+		return errMsg
+	}
+
+	method := "merge"
+	for _, parameter := range parameters[1:] {
+		switch parameter {
+		case "--squash":
+			method = "squash"
+		case "--rebase":
+			method = "rebase"
+		default:
+			return usage
+		}
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	if _, resp, err := githubClient.PullRequests.Merge(ctx, owner, repo, number, "", &github.PullRequestOptions{MergeMethod: method}); err != nil {
+		return "Failed to merge the pull request: " + getActionFailReason(resp, err, "merge pull requests", repoName, userInfo.GitHubUsername)
+	}
+
+	return fmt.Sprintf("You merged [%s#%d](%s%s/pull/%d).", repoName, number, p.getBaseURL(), repoName, number)
+}
+
+func (p *Plugin) handlePullRequestRequestReview(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify a pull request and reviewers, e.g. `/github pr request-review owner/repo#1 @user`."
+	owner, repo, number, errMsg := p.parsePullRequestRef(args.ChannelId, parameters, usage)
+	if errMsg != "" {
+		return errMsg
+	}
+	if len(parameters) < 2 {
+		return usage
+	}
+
+	reviewers := make([]string, 0, len(parameters)-1)
+	for _, reviewer := range parameters[1:] {
+		login, err := p.resolveGitHubLogin(reviewer)
+		if err != nil {
+			return err.Error()
+		}
+		reviewers = append(reviewers, login)
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	repoName := fullNameFromOwnerAndRepo(owner, repo)
+
+	if _, resp, err := githubClient.PullRequests.RequestReviewers(ctx, owner, repo, number, github.ReviewersRequest{Reviewers: reviewers}); err != nil {
+		return "Failed to request reviews: " + getActionFailReason(resp, err, "request reviews", repoName, userInfo.GitHubUsername)
+	}
+
+	return fmt.Sprintf("Requested reviews from %s on [%s#%d](%s%s/pull/%d).", strings.Join(reviewers, ", "), repoName, number, p.getBaseURL(), repoName, number)
+}
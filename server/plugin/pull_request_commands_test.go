@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetPullRequestListQuery(t *testing.T) {
+	assert.Equal(t, "is:pr is:open archived:false repo:mattermost/mattermost-server", getPullRequestListQuery("mattermost", "mattermost-server", "octocat", false, false))
+	assert.Equal(t, "is:pr is:open archived:false org:mattermost author:octocat", getPullRequestListQuery("mattermost", "", "octocat", true, false))
+	assert.Equal(t, "is:pr is:open archived:false review-requested:octocat", getPullRequestListQuery("", "", "octocat", false, true))
+}
+
+func TestPullRequestCommands(t *testing.T) {
+	var received map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/1/merge", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{"merged": true}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/1/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/2/merge", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprint(w, `{"message": "Pull Request is not mergeable"}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server/pulls/3/merge", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "Must have admin rights to Repository."}`)
+	})
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+	userInfo := serveTestGitHub(t, p, nil, mux)
+	require.NoError(t, p.storeGitHubUserInfo(&GitHubUserInfo{UserID: "alice", GitHubUsername: "alice-gh", Token: &oauth2.Token{AccessToken: "token"}}))
+	api.On("GetUserByUsername", "alice").Return(&model.User{Id: "alice"}, nil)
+	api.On("GetUserByUsername", "bob").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	api.On("GetUserByUsername", "carol").Return(&model.User{Id: "carol"}, nil)
+
+	run := func(parameters ...string) string {
+		return p.handlePullRequest(nil, &model.CommandArgs{UserId: "user"}, parameters, userInfo)
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		msg := run("approve", "mattermost/mattermost-server#1", "Looks", "good")
+		assert.Equal(t, fmt.Sprintf("You approved [mattermost/mattermost-server#1](%smattermost/mattermost-server/pull/1).", p.getBaseURL()), msg)
+		assert.Equal(t, "APPROVE", received["event"])
+		assert.Equal(t, "Looks good", received["body"])
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		run("merge", "mattermost/mattermost-server#1", "--squash")
+		assert.Equal(t, "squash", received["merge_method"])
+
+		msg := run("merge", "mattermost/mattermost-server#2")
+		assert.Equal(t, "Failed to merge the pull request: Pull Request is not mergeable", msg)
+
+		msg = run("merge", "mattermost/mattermost-server#3")
+		assert.Equal(t, "Failed to merge the pull request: Sorry, you don't have enough permissions to merge pull requests in the repo mattermost/mattermost-server with the user octocat", msg)
+
+		assert.Equal(t, "Please specify a pull request, e.g. `/github pr merge owner/repo#1 [--squash|--rebase]`.", run("merge", "mattermost/mattermost-server#1", "--fast-forward"))
+	})
+
+	t.Run("request review", func(t *testing.T) {
+		msg := run("request-review", "mattermost/mattermost-server#1", "@alice", "hubot")
+		assert.Contains(t, msg, "Requested reviews from alice-gh, hubot")
+		assert.Equal(t, []interface{}{"alice-gh", "hubot"}, received["reviewers"])
+
+		assert.Equal(t, "@bob is not a Mattermost user.", run("request-review", "mattermost/mattermost-server#1", "@bob"))
+		assert.Equal(t, "@carol has not connected their GitHub account.", run("request-review", "mattermost/mattermost-server#1", "@carol"))
+	})
+
+	t.Run("list requires a repository or filter", func(t *testing.T) {
+		assert.Equal(t, "Please specify a repository, or --mine or --review-requested to list pull requests of all repositories.", run("list"))
+		assert.Equal(t, "Unknown flag --all. Supported flags are --mine and --review-requested.", run("list", "--all"))
+	})
+}
@@ -0,0 +1,250 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	postPropGitHubObject = "gh_object"
+	postPropGitHubRepo   = "gh_repo"
+	postPropGitHubNumber = "gh_number"
+
+	postPropGitHubCommentID = "gh_comment_id"
+
+	githubObjectIssue         = "issue"
+	githubObjectIssueComment  = "issue_comment"
+	githubObjectReviewComment = "review_comment"
+
+	reactionKey = "reaction_"
+
+	defaultReactionMapping = "+1:+1,thumbsup:+1,-1:-1,thumbsdown:-1,smile:laugh,laughing:laugh,tada:hooray,confused:confused,heart:heart,rocket:rocket,eyes:eyes"
+)
+
+// githubReactions are the reactions supported by GitHub.
+var githubReactions = []string{"+1", "-1", "laugh", "confused", "heart", "hooray", "rocket", "eyes"}
+
+// GitHubObject identifies the issue, pull request or comment a post represents.
+// Pull requests are issues as far as reactions are concerned.
+type GitHubObject struct {
+	Type      string
+	Owner     string
+	Repo      string
+	Number    int
+	CommentID int64
+}
+
+// setGitHubObjectProps records on post which GitHub object it represents.
+// commentID is ignored for issues and pull requests.
+func setGitHubObjectProps(post *model.Post, objectType, repoName string, number int, commentID int64) {
+	post.AddProp(postPropGitHubObject, objectType)
+	post.AddProp(postPropGitHubRepo, repoName)
+	post.AddProp(postPropGitHubNumber, strconv.Itoa(number))
+	if objectType != githubObjectIssue {
+		post.AddProp(postPropGitHubCommentID, strconv.FormatInt(commentID, 10))
+	}
+}
+
+// getGitHubObject returns the GitHub object recorded on post, or nil if there is none.
+func getGitHubObject(post *model.Post) *GitHubObject {
+	objectType, _ := post.GetProp(postPropGitHubObject).(string)
+	repoName, _ := post.GetProp(postPropGitHubRepo).(string)
+	numberProp, _ := post.GetProp(postPropGitHubNumber).(string)
+	if objectType == "" {
+		return nil
+	}
+
+	owner, repo, err := parseRepo(repoName)
+	if err != nil {
+		return nil
+	}
+
+	number, err := strconv.Atoi(numberProp)
+	if err != nil {
+		return nil
+	}
+
+	object := &GitHubObject{Type: objectType, Owner: owner, Repo: repo, Number: number}
+	if objectType != githubObjectIssue {
+		commentIDProp, _ := post.GetProp(postPropGitHubCommentID).(string)
+		object.CommentID, err = strconv.ParseInt(commentIDProp, 10, 64)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return object
+}
+
+// parseReactionMapping parses a comma-separated list of emoji:reaction pairs.
+// An empty mapping falls back to the default one.
+func parseReactionMapping(mapping string) (map[string]string, error) {
+	if strings.TrimSpace(mapping) == "" {
+		mapping = defaultReactionMapping
+	}
+
+	reactions := map[string]string{}
+	for _, pair := range strings.Split(mapping, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 {
+			return nil, errors.Errorf("invalid reaction mapping %q, expected emoji:reaction", pair)
+		}
+
+		emoji := strings.Trim(strings.TrimSpace(pair[:i]), ":")
+		reaction := strings.TrimSpace(pair[i+1:])
+		if exists, _ := ItemExists(githubReactions, reaction); !exists {
+			return nil, errors.Errorf("invalid GitHub reaction %q, must be one of %s", reaction, strings.Join(githubReactions, ", "))
+		}
+
+		reactions[emoji] = reaction
+	}
+
+	return reactions, nil
+}
+
+// getGitHubReaction returns the GitHub reaction the given emoji maps to, if any.
+// An invalid mapping falls back to the default one.
+func (p *Plugin) getGitHubReaction(emojiName string) string {
+	reactions, err := parseReactionMapping(p.getConfiguration().ReactionMapping)
+	if err != nil {
+		reactions, _ = parseReactionMapping(defaultReactionMapping)
+	}
+
+	return reactions[emojiName]
+}
+
+// getReactionKey returns the key of the ID of the GitHub reaction a user added through post.
+func getReactionKey(postID, userID, reaction string) string {
+	return fmt.Sprintf("%s%x", reactionKey, sha256.Sum256([]byte(postID+userID+reaction)))
+}
+
+// ReactionHasBeenAdded adds the corresponding GitHub reaction to the GitHub object the post represents.
+func (p *Plugin) ReactionHasBeenAdded(c *plugin.Context, reaction *model.Reaction) {
+	object, githubReaction, info := p.getReactionContext(reaction)
+	if object == nil {
+		return
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, info)
+
+	var result *github.Reaction
+	var err error
+	switch object.Type {
+	case githubObjectIssue:
+		result, _, err = githubClient.Reactions.CreateIssueReaction(ctx, object.Owner, object.Repo, object.Number, githubReaction)
+	case githubObjectIssueComment:
+		result, _, err = githubClient.Reactions.CreateIssueCommentReaction(ctx, object.Owner, object.Repo, object.CommentID, githubReaction)
+	case githubObjectReviewComment:
+		result, _, err = githubClient.Reactions.CreatePullRequestCommentReaction(ctx, object.Owner, object.Repo, object.CommentID, githubReaction)
+	default:
+		return
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to add GitHub reaction", "post_id", reaction.PostId, "error", err.Error())
+		return
+	}
+
+	key := getReactionKey(reaction.PostId, reaction.UserId, githubReaction)
+	if appErr := p.API.KVSet(key, []byte(strconv.FormatInt(result.GetID(), 10))); appErr != nil {
+		p.API.LogWarn("Failed to store GitHub reaction", "post_id", reaction.PostId, "error", appErr.Error())
+	}
+}
+
+// ReactionHasBeenRemoved removes the corresponding GitHub reaction, unless the user
+// still has another reaction on the post mapping to it.
+func (p *Plugin) ReactionHasBeenRemoved(c *plugin.Context, reaction *model.Reaction) {
+	object, githubReaction, info := p.getReactionContext(reaction)
+	if object == nil {
+		return
+	}
+
+	reactions, appErr := p.API.GetReactions(reaction.PostId)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get reactions", "post_id", reaction.PostId, "error", appErr.Error())
+		return
+	}
+	for _, other := range reactions {
+		if other.UserId == reaction.UserId && other.EmojiName != reaction.EmojiName && p.getGitHubReaction(other.EmojiName) == githubReaction {
+			return
+		}
+	}
+
+	key := getReactionKey(reaction.PostId, reaction.UserId, githubReaction)
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil || data == nil {
+		return
+	}
+	reactionID, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, info)
+
+	switch object.Type {
+	case githubObjectIssue:
+		_, err = githubClient.Reactions.DeleteIssueReaction(ctx, object.Owner, object.Repo, object.Number, reactionID)
+	case githubObjectIssueComment:
+		_, err = githubClient.Reactions.DeleteIssueCommentReaction(ctx, object.Owner, object.Repo, object.CommentID, reactionID)
+	case githubObjectReviewComment:
+		_, err = githubClient.Reactions.DeletePullRequestCommentReaction(ctx, object.Owner, object.Repo, object.CommentID, reactionID)
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to remove GitHub reaction", "post_id", reaction.PostId, "error", err.Error())
+		return
+	}
+
+	if appErr := p.API.KVDelete(key); appErr != nil {
+		p.API.LogWarn("Failed to delete GitHub reaction", "post_id", reaction.PostId, "error", appErr.Error())
+	}
+}
+
+// getReactionContext returns the GitHub object and reaction a Mattermost reaction applies to,
+// and the GitHub account of the user who reacted. A nil object is returned if the reaction
+// has no GitHub counterpart or the user isn't connected.
+func (p *Plugin) getReactionContext(reaction *model.Reaction) (*GitHubObject, string, *GitHubUserInfo) {
+	if reaction.UserId == p.BotUserID {
+		return nil, "", nil
+	}
+
+	githubReaction := p.getGitHubReaction(reaction.EmojiName)
+	if githubReaction == "" {
+		return nil, "", nil
+	}
+
+	post, appErr := p.API.GetPost(reaction.PostId)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get post of reaction", "post_id", reaction.PostId, "error", appErr.Error())
+		return nil, "", nil
+	}
+	if post.UserId != p.BotUserID {
+		return nil, "", nil
+	}
+
+	object := getGitHubObject(post)
+	if object == nil {
+		return nil, "", nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(reaction.UserId)
+	if apiErr != nil {
+		return nil, "", nil
+	}
+
+	return object, githubReaction, info
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReactionMapping(t *testing.T) {
+	t.Run("default mapping", func(t *testing.T) {
+		reactions, err := parseReactionMapping("")
+		require.NoError(t, err)
+		assert.Equal(t, "+1", reactions["thumbsup"])
+		assert.Equal(t, "hooray", reactions["tada"])
+		assert.Equal(t, "eyes", reactions["eyes"])
+	})
+
+	t.Run("custom mapping", func(t *testing.T) {
+		reactions, err := parseReactionMapping(" :white_check_mark::+1 , party_parrot:hooray,")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"white_check_mark": "+1", "party_parrot": "hooray"}, reactions)
+	})
+
+	t.Run("invalid reaction", func(t *testing.T) {
+		_, err := parseReactionMapping("tada:party")
+		assert.Error(t, err)
+	})
+
+	t.Run("missing reaction", func(t *testing.T) {
+		_, err := parseReactionMapping("tada")
+		assert.Error(t, err)
+	})
+}
+
+func TestGetGitHubReaction(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{ReactionMapping: "party_parrot:hooray"})
+	assert.Equal(t, "hooray", p.getGitHubReaction("party_parrot"))
+	assert.Empty(t, p.getGitHubReaction("tada"))
+
+	p.setConfiguration(&Configuration{ReactionMapping: "tada:party"})
+	assert.Equal(t, "hooray", p.getGitHubReaction("tada"), "an invalid mapping falls back to the default one")
+}
+
+func TestGitHubObjectProps(t *testing.T) {
+	t.Run("issue", func(t *testing.T) {
+		post := &model.Post{}
+		setGitHubObjectProps(post, githubObjectIssue, "mattermost/mattermost-server", 42, 0)
+
+		assert.Equal(t, &GitHubObject{Type: githubObjectIssue, Owner: "mattermost", Repo: "mattermost-server", Number: 42}, getGitHubObject(post))
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		post := &model.Post{}
+		setGitHubObjectProps(post, githubObjectIssueComment, "mattermost/mattermost-server", 42, 123456789012)
+
+		assert.Equal(t, &GitHubObject{Type: githubObjectIssueComment, Owner: "mattermost", Repo: "mattermost-server", Number: 42, CommentID: 123456789012}, getGitHubObject(post))
+	})
+
+	t.Run("no object", func(t *testing.T) {
+		assert.Nil(t, getGitHubObject(&model.Post{}))
+	})
+}
@@ -0,0 +1,273 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	subscriptionCleanupJobKey = "subscription_cleanup_job"
+
+	// subscriptionCleanupInterval is how often orphaned subscriptions are looked for.
+	subscriptionCleanupInterval = 24 * time.Hour
+
+	subscriptionCleanupNoticeKey = "subscriptioncleanupnotice_"
+	// subscriptionCleanupNoticeTTL is how long to wait before repeating a notice that a subscription couldn't be reassigned.
+	subscriptionCleanupNoticeTTL = 7 * 24 * time.Hour
+
+	channelMembersPerPage = 100
+)
+
+// scheduleSubscriptionCleanup schedules cleanupSubscriptions to run periodically on one node of the cluster.
+func (p *Plugin) scheduleSubscriptionCleanup() error {
+	job, err := cluster.Schedule(p.API, subscriptionCleanupJobKey, cluster.MakeWaitForRoundedInterval(subscriptionCleanupInterval), p.cleanupSubscriptions)
+	if err != nil {
+		return errors.Wrap(err, "could not schedule subscription cleanup")
+	}
+
+	p.subscriptionCleanupJob = job
+
+	return nil
+}
+
+// cleanupSubscriptions removes subscriptions of archived channels and reassigns subscriptions
+// whose creator can no longer use them to a connected channel admin.
+func (p *Plugin) cleanupSubscriptions() {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions for cleanup", "error", err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	for repository, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if err := p.cleanupSubscription(ctx, repository, sub); err != nil {
+				p.API.LogWarn("Failed to clean up subscription", "channel_id", sub.ChannelID, "repo", repository, "error", err.Error())
+			}
+		}
+	}
+}
+
+// cleanupSubscription removes or reassigns sub, a subscription of repository, if needed.
+func (p *Plugin) cleanupSubscription(ctx context.Context, repository string, sub *Subscription) error {
+	channel, appErr := p.API.GetChannel(sub.ChannelID)
+	if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+		return errors.Wrap(appErr, "could not get channel")
+	}
+
+	if appErr != nil || channel.DeleteAt != 0 {
+		removed, err := p.removeSubscription(sub.ChannelID, repository)
+		if err != nil {
+			return err
+		}
+		if removed != nil {
+			p.storeSubscriptionChange("", sub.ChannelID, removed.Repository, removed, nil)
+		}
+		return nil
+	}
+
+	if sub.CreatorID == "" {
+		return nil
+	}
+
+	owner, repo := parseOwnerAndRepo(repository, "")
+	if isRepositoryPattern(repo) {
+		// Like organization subscriptions, patterns only require a connected account.
+		repo = ""
+	}
+	reason, err := p.getCreatorAccessLoss(ctx, sub.CreatorID, owner, repo)
+	if err != nil || reason == "" {
+		return err
+	}
+
+	creatorID, err := p.findSubscriptionCreator(ctx, sub, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	if creatorID == "" {
+		p.postSubscriptionCleanupNotice(sub, repository, fmt.Sprintf(
+			"The subscription of this channel to `%s` was created by %s, who %s. No connected channel admin could take it over, so events that require access to private repositories aren't posted. A channel admin can run `/github subscriptions add %s %s` to fix this.",
+			strings.Trim(repository, "/"), p.getUserMention(sub.CreatorID), reason, strings.Trim(repository, "/"), describeSubscription(sub)))
+		return nil
+	}
+
+	before, after, err := p.reassignSubscription(repository, sub, creatorID)
+	if err != nil || after == nil {
+		return err
+	}
+	p.storeSubscriptionChange("", sub.ChannelID, repository, before, after)
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Message: fmt.Sprintf("The subscription of this channel to `%s` was reassigned from %s, who %s, to %s.",
+			strings.Trim(repository, "/"), p.getUserMention(sub.CreatorID), reason, p.getUserMention(creatorID)),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscription reassignment", "channel_id", sub.ChannelID, "error", appErr.Error())
+	}
+
+	return nil
+}
+
+// reassignSubscription changes the creator of the subscription of the channel of sub to repository to creatorID.
+// Only the creator is changed, so that changes made since sub was read aren't reverted. The subscription is left
+// alone if it was deleted or reassigned in the meantime, in which case nil is returned.
+func (p *Plugin) reassignSubscription(repository string, sub *Subscription, creatorID string) (before, after *Subscription, err error) {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	err = client.KV.SetAtomicWithRetries(getRepoSubscriptionsKey(repository), func(oldValue []byte) (interface{}, error) {
+		repoSubs, err := decodeSubscriptions(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		before, after = nil, nil
+		for i, s := range repoSubs {
+			if s.ChannelID == sub.ChannelID && s.CreatorID == sub.CreatorID {
+				reassigned := *s
+				reassigned.CreatorID = creatorID
+				before, after = s, &reassigned
+				repoSubs[i] = after
+			}
+		}
+
+		return repoSubs, nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not reassign subscription")
+	}
+
+	if after != nil {
+		p.subscriptionsChanged()
+	}
+
+	return before, after, nil
+}
+
+// getCreatorAccessLoss returns why userID can no longer create subscriptions to owner[/repo], or an empty string if it still can.
+// Organization subscriptions only require a connected account.
+func (p *Plugin) getCreatorAccessLoss(ctx context.Context, userID, owner, repo string) (string, error) {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		if appErr.StatusCode == http.StatusNotFound {
+			return "was deleted", nil
+		}
+		return "", errors.Wrap(appErr, "could not get creator")
+	}
+	if user.DeleteAt != 0 {
+		return "was deactivated", nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		if apiErr.ID == apiErrorIDNotConnected {
+			return "disconnected their GitHub account", nil
+		}
+		return "", errors.Wrap(apiErr, "could not get GitHub account of creator")
+	}
+
+	if repo == "" {
+		return "", nil
+	}
+
+	allowed, err := p.hasRepositoryAccess(ctx, info, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "no longer has access to the repository", nil
+	}
+
+	return "", nil
+}
+
+// hasRepositoryAccess reports whether the GitHub account of info can access owner/repo.
+// Unlike permissionToRepo, failures other than a missing permission are returned as errors.
+func (p *Plugin) hasRepositoryAccess(ctx context.Context, info *GitHubUserInfo, owner, repo string) (bool, error) {
+	githubClient := p.githubConnectUser(ctx, info)
+
+	_, resp, err := githubClient.Repositories.Get(ctx, owner, repo)
+	if err == nil {
+		return true, nil
+	}
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+		return false, nil
+	}
+
+	return false, errors.Wrap(err, "could not get repository")
+}
+
+// findSubscriptionCreator returns a channel admin of the channel of sub who is connected to GitHub
+// and can access owner[/repo], or an empty string if there is none.
+func (p *Plugin) findSubscriptionCreator(ctx context.Context, sub *Subscription, owner, repo string) (string, error) {
+	for page := 0; ; page++ {
+		members, appErr := p.API.GetChannelMembers(sub.ChannelID, page, channelMembersPerPage)
+		if appErr != nil {
+			return "", errors.Wrap(appErr, "could not get channel members")
+		}
+
+		for _, member := range members {
+			if !member.SchemeAdmin || member.UserId == sub.CreatorID {
+				continue
+			}
+
+			reason, err := p.getCreatorAccessLoss(ctx, member.UserId, owner, repo)
+			if err != nil {
+				p.API.LogDebug("Failed to check channel admin as subscription creator", "user_id", member.UserId, "error", err.Error())
+				continue
+			}
+			if reason == "" {
+				return member.UserId, nil
+			}
+		}
+
+		if len(members) < channelMembersPerPage {
+			return "", nil
+		}
+	}
+}
+
+// postSubscriptionCleanupNotice posts message in the channel of sub, unless it was already posted recently.
+func (p *Plugin) postSubscriptionCleanupNotice(sub *Subscription, repository, message string) {
+	key := fmt.Sprintf("%s%x", subscriptionCleanupNoticeKey, sha256.Sum256([]byte(sub.ChannelID+repository)))
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil || data != nil {
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Message:   message,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscription cleanup notice", "channel_id", sub.ChannelID, "error", appErr.Error())
+		return
+	}
+
+	if appErr := p.API.KVSetWithExpiry(key, []byte("1"), int64(subscriptionCleanupNoticeTTL/time.Second)); appErr != nil {
+		p.API.LogWarn("Failed to store subscription cleanup notice", "channel_id", sub.ChannelID, "error", appErr.Error())
+	}
+}
+
+// getUserMention returns an @-mention of userID, or the ID itself if the user can't be found.
+func (p *Plugin) getUserMention(userID string) string {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return userID
+	}
+
+	return "@" + user.Username
+}
@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestCleanupSubscriptions(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "archived", CreatorID: "creator", Repository: "mattermost/", Features: "pulls"},
+		{ChannelID: "deleted", CreatorID: "creator", Repository: "mattermost/", Features: "pulls"},
+		{ChannelID: "reassign", CreatorID: "deactivated", Repository: "mattermost/", Features: "pulls"},
+		{ChannelID: "noadmin", CreatorID: "deactivated", Repository: "mattermost/", Features: "issues"},
+		{ChannelID: "active", CreatorID: "creator", Repository: "mattermost/", Features: "pulls"},
+	})
+	p.setConfiguration(&Configuration{EncryptionKey: "0123456789abcdef"})
+	api := p.API.(*plugintest.API)
+
+	for _, userID := range []string{"creator", "admin"} {
+		require.NoError(t, p.storeGitHubUserInfo(&GitHubUserInfo{UserID: userID, Token: &oauth2.Token{AccessToken: "token"}}))
+	}
+
+	api.On("GetChannel", "archived").Return(&model.Channel{Id: "archived", DeleteAt: 1}, nil)
+	api.On("GetChannel", "deleted").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	api.On("GetChannel", mock.Anything).Return(&model.Channel{}, nil)
+	api.On("GetUser", "creator").Return(&model.User{Id: "creator", Username: "creator"}, nil)
+	api.On("GetUser", "admin").Return(&model.User{Id: "admin", Username: "admin"}, nil)
+	api.On("GetUser", "member").Return(&model.User{Id: "member", Username: "member"}, nil)
+	api.On("GetUser", "deactivated").Return(&model.User{Id: "deactivated", Username: "gone", DeleteAt: 1}, nil)
+	api.On("GetChannelMembers", "reassign", 0, channelMembersPerPage).Return(model.ChannelMembers{
+		{UserId: "member"},
+		{UserId: "deactivated", SchemeAdmin: true},
+		{UserId: "admin", SchemeAdmin: true},
+	}, nil)
+	api.On("GetChannelMembers", "noadmin", 0, channelMembersPerPage).Return(model.ChannelMembers{{UserId: "member"}}, nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "reassign" && post.Message == "The subscription of this channel to `mattermost` was reassigned from @gone, who was deactivated, to @admin."
+	})).Return(&model.Post{}, nil).Once()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "noadmin"
+	})).Return(&model.Post{}, nil).Once()
+
+	p.cleanupSubscriptions()
+
+	subs, err := p.getRepoSubscriptions("mattermost/")
+	require.NoError(t, err)
+	creators := map[string]string{}
+	for _, sub := range subs {
+		creators[sub.ChannelID] = sub.CreatorID
+	}
+	assert.Equal(t, map[string]string{"reassign": "admin", "noadmin": "deactivated", "active": "creator"}, creators)
+
+	history, err := p.getSubscriptionHistory("archived")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, subscriptionChangeDelete, history[0].Action)
+	assert.Empty(t, history[0].ActorID)
+
+	history, err = p.getSubscriptionHistory("reassign")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "admin", history[0].After.CreatorID)
+
+	t.Run("notices are not repeated", func(t *testing.T) {
+		p.cleanupSubscriptions()
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+}
+
+func TestReassignSubscription(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "channel", CreatorID: "deactivated", Repository: "mattermost/mattermost-server", Features: "pulls"},
+	})
+	stale := &Subscription{ChannelID: "channel", CreatorID: "deactivated", Repository: "mattermost/mattermost-server", Features: "issues"}
+
+	t.Run("only the creator is changed", func(t *testing.T) {
+		before, after, err := p.reassignSubscription("mattermost/mattermost-server", stale, "admin")
+		require.NoError(t, err)
+		require.NotNil(t, after)
+		assert.Equal(t, "deactivated", before.CreatorID)
+
+		subs, err := p.getRepoSubscriptions("mattermost/mattermost-server")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "admin", subs[0].CreatorID)
+		assert.Equal(t, "pulls", subs[0].Features)
+	})
+
+	t.Run("subscriptions reassigned meanwhile are left alone", func(t *testing.T) {
+		_, after, err := p.reassignSubscription("mattermost/mattermost-server", stale, "other")
+		require.NoError(t, err)
+		assert.Nil(t, after)
+
+		subs, err := p.getRepoSubscriptions("mattermost/mattermost-server")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", subs[0].CreatorID)
+	})
+}
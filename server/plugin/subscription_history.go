@@ -0,0 +1,203 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	subscriptionHistoryKey = "_subscriptionhistory"
+
+	// subscriptionHistoryLimit is the number of changes kept per channel. Older changes are dropped.
+	subscriptionHistoryLimit = 100
+	// subscriptionHistoryPageSize is the number of changes shown by /github subscriptions history.
+	subscriptionHistoryPageSize = 20
+
+	subscriptionChangeAdd    = "add"
+	subscriptionChangeUpdate = "update"
+	subscriptionChangeDelete = "delete"
+)
+
+// SubscriptionChange records a change of the subscription of a channel to a repository or organization.
+// Before is nil for added subscriptions and After is nil for deleted ones.
+// An empty ActorID means the change was made by the plugin itself.
+type SubscriptionChange struct {
+	Action     string        `json:"action"`
+	ActorID    string        `json:"actor_id"`
+	ChannelID  string        `json:"channel_id"`
+	Repository string        `json:"repository"`
+	Timestamp  int64         `json:"timestamp"`
+	Before     *Subscription `json:"before,omitempty"`
+	After      *Subscription `json:"after,omitempty"`
+}
+
+// subscriptionsEqual reports whether a and b would post the same events, created by the same user.
+func subscriptionsEqual(a, b *Subscription) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Features == b.Features && a.Flags.String() == b.Flags.String() && a.CreatorID == b.CreatorID
+}
+
+// recordSubscriptionChange appends a change to the history of the channel and, if enabled, announces it in the channel.
+func (p *Plugin) recordSubscriptionChange(actorID, channelID, repository string, before, after *Subscription) {
+	change := p.storeSubscriptionChange(actorID, channelID, repository, before, after)
+	if change == nil || !p.getConfiguration().EnableSubscriptionChangeMessages {
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channelID,
+		Message:   p.describeSubscriptionChange(change, map[string]string{}),
+		Type:      model.PostTypeSystemGeneric,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscription change", "channel_id", channelID, "error", appErr.Error())
+	}
+}
+
+// storeSubscriptionChange appends a change to the history of the channel and returns it.
+// Nil is returned if before and after are equal. Failures are logged, as the subscription itself is already stored.
+func (p *Plugin) storeSubscriptionChange(actorID, channelID, repository string, before, after *Subscription) *SubscriptionChange {
+	if subscriptionsEqual(before, after) {
+		return nil
+	}
+
+	change := &SubscriptionChange{
+		Action:     subscriptionChangeUpdate,
+		ActorID:    actorID,
+		ChannelID:  channelID,
+		Repository: repository,
+		Timestamp:  model.GetMillis(),
+		Before:     before,
+		After:      after,
+	}
+	switch {
+	case before == nil:
+		change.Action = subscriptionChangeAdd
+	case after == nil:
+		change.Action = subscriptionChangeDelete
+	}
+
+	client := pluginapi.NewClient(p.API, p.Driver)
+	err := client.KV.SetAtomicWithRetries(channelID+subscriptionHistoryKey, func(oldValue []byte) (interface{}, error) {
+		history, err := decodeSubscriptionHistory(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, change)
+		if len(history) > subscriptionHistoryLimit {
+			history = history[len(history)-subscriptionHistoryLimit:]
+		}
+
+		return history, nil
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to store subscription change", "channel_id", channelID, "repo", repository, "error", err.Error())
+	}
+
+	return change
+}
+
+func decodeSubscriptionHistory(data []byte) ([]*SubscriptionChange, error) {
+	var history []*SubscriptionChange
+	if len(data) == 0 {
+		return history, nil
+	}
+
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal subscription history")
+	}
+
+	return history, nil
+}
+
+// getSubscriptionHistory returns the recorded subscription changes of a channel, oldest first.
+func (p *Plugin) getSubscriptionHistory(channelID string) ([]*SubscriptionChange, error) {
+	data, appErr := p.API.KVGet(channelID + subscriptionHistoryKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get subscription history from KVStore")
+	}
+
+	return decodeSubscriptionHistory(data)
+}
+
+// describeSubscription returns the features and flags of sub as given to /github subscriptions add.
+func describeSubscription(sub *Subscription) string {
+	description := sub.Features
+	if flags := sub.Flags.String(); flags != "" {
+		description += " " + flags
+	}
+
+	return description
+}
+
+// describeSubscriptionChange returns a markdown description of change. Usernames are cached in usernames.
+func (p *Plugin) describeSubscriptionChange(change *SubscriptionChange, usernames map[string]string) string {
+	mention := func(userID string) string {
+		username, ok := usernames[userID]
+		if !ok {
+			username = userID
+			if user, appErr := p.API.GetUser(userID); appErr == nil {
+				username = user.Username
+			}
+			usernames[userID] = username
+		}
+		return "@" + username
+	}
+
+	actor := "The GitHub plugin"
+	if change.ActorID != "" {
+		actor = mention(change.ActorID)
+	}
+
+	repository := strings.Trim(change.Repository, "/")
+	switch {
+	case change.Action == subscriptionChangeAdd:
+		return fmt.Sprintf("%s subscribed this channel to `%s` with `%s`.", actor, repository, describeSubscription(change.After))
+	case change.Action == subscriptionChangeDelete:
+		return fmt.Sprintf("%s unsubscribed this channel from `%s`, which had `%s`.", actor, repository, describeSubscription(change.Before))
+	case describeSubscription(change.Before) == describeSubscription(change.After):
+		return fmt.Sprintf("%s reassigned the subscription of this channel to `%s` from %s to %s.", actor, repository, mention(change.Before.CreatorID), mention(change.After.CreatorID))
+	default:
+		return fmt.Sprintf("%s changed the subscription of this channel to `%s` from `%s` to `%s`.", actor, repository, describeSubscription(change.Before), describeSubscription(change.After))
+	}
+}
+
+func (p *Plugin) handleSubscriptionsHistory(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	history, err := p.getSubscriptionHistory(args.ChannelId)
+	if err != nil {
+		p.API.LogWarn("Failed to get subscription history", "channel_id", args.ChannelId, "error", err.Error())
+		return "Failed to get the subscription history of this channel."
+	}
+
+	if len(history) == 0 {
+		return "No subscription changes have been recorded in this channel."
+	}
+
+	// Show the most recent changes first.
+	start := len(history) - subscriptionHistoryPageSize
+	if start < 0 {
+		start = 0
+	}
+
+	txt := "### Subscription changes in this channel\n"
+	usernames := map[string]string{}
+	for i := len(history) - 1; i >= start; i-- {
+		change := history[i]
+		timestamp := time.Unix(0, change.Timestamp*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04 MST")
+		txt += fmt.Sprintf("* %s: %s\n", timestamp, p.describeSubscriptionChange(change, usernames))
+	}
+
+	return txt
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHistory(t *testing.T) {
+	p := pluginWithMockedSubs(nil)
+	p.setConfiguration(&Configuration{})
+	api := p.API.(*plugintest.API)
+	api.On("GetUser", "alice").Return(&model.User{Id: "alice", Username: "alice"}, nil)
+	api.On("GetUser", "bob").Return(&model.User{Id: "bob", Username: "bob"}, nil)
+
+	require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "1", CreatorID: "alice", Repository: "mattermost/mattermost-server", Features: "pulls"}))
+	require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "1", CreatorID: "alice", Repository: "mattermost/mattermost-server", Features: "pulls"}))
+	require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "1", CreatorID: "alice", Repository: "mattermost/mattermost-server", Features: "pulls,issues"}))
+	require.NoError(t, p.Unsubscribe("1", "mattermost/mattermost-server", "bob"))
+	require.NoError(t, p.Unsubscribe("1", "mattermost/mattermost-server", "bob"))
+
+	history, err := p.getSubscriptionHistory("1")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	assert.Equal(t, subscriptionChangeAdd, history[0].Action)
+	assert.Equal(t, "alice", history[0].ActorID)
+	assert.Nil(t, history[0].Before)
+
+	assert.Equal(t, subscriptionChangeUpdate, history[1].Action)
+	assert.Equal(t, "pulls", history[1].Before.Features)
+	assert.Equal(t, "pulls,issues", history[1].After.Features)
+
+	assert.Equal(t, subscriptionChangeDelete, history[2].Action)
+	assert.Equal(t, "bob", history[2].ActorID)
+	assert.Nil(t, history[2].After)
+
+	msg := p.handleSubscriptionsHistory(nil, &model.CommandArgs{ChannelId: "1"}, nil, nil)
+	assert.Contains(t, msg, "@bob unsubscribed this channel from `mattermost/mattermost-server`, which had `pulls,issues`.")
+	assert.Contains(t, msg, "@alice changed the subscription of this channel to `mattermost/mattermost-server` from `pulls` to `pulls,issues`.")
+	assert.Contains(t, msg, "@alice subscribed this channel to `mattermost/mattermost-server` with `pulls`.")
+
+	t.Run("history is bounded", func(t *testing.T) {
+		for i := 0; i < subscriptionHistoryLimit; i++ {
+			require.NoError(t, p.AddSubscription("mattermost/", &Subscription{ChannelID: "1", CreatorID: "alice", Repository: "mattermost/"}))
+			require.NoError(t, p.Unsubscribe("1", "mattermost", "alice"))
+		}
+
+		history, err := p.getSubscriptionHistory("1")
+		require.NoError(t, err)
+		assert.Len(t, history, subscriptionHistoryLimit)
+		assert.Equal(t, subscriptionChangeDelete, history[len(history)-1].Action)
+	})
+
+	t.Run("changes are posted in the channel if enabled", func(t *testing.T) {
+		p.setConfiguration(&Configuration{EnableSubscriptionChangeMessages: true})
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "2" && post.Type == model.PostTypeSystemGeneric &&
+				post.Message == "@alice subscribed this channel to `mattermost/mattermost-webapp` with `issues --exclude-org-member`."
+		})).Return(&model.Post{}, nil).Once()
+
+		require.NoError(t, p.AddSubscription("mattermost/mattermost-webapp", &Subscription{ChannelID: "2", CreatorID: "alice", Repository: "mattermost/mattermost-webapp", Features: "issues", Flags: SubscriptionFlags{ExcludeOrgMembers: true}}))
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+}
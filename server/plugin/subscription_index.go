@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+)
+
+const clusterEventSubscriptionsChanged = "subscriptions_changed"
+
+// subscriptionIndex keeps the subscriptions of all repositories and organizations in memory,
+// so that dispatching a webhook event doesn't need to read the KV store. It is loaded lazily
+// and dropped whenever subscriptions change on any node of the cluster.
+type subscriptionIndex struct {
+	lock sync.RWMutex
+
+	// repositories maps repository and organization keys, as used by AddSubscription, to their subscriptions.
+	// It is nil until loaded.
+	repositories map[string][]*Subscription
+	// patterns maps organizations to the keys of their repository pattern subscriptions, like owner/payments-*.
+	patterns map[string][]string
+}
+
+// getSubscriptionIndex returns the subscriptions of all repositories and organizations, and the repository
+// patterns subscribed to per organization, loading them if needed. The returned maps must not be modified.
+func (p *Plugin) getSubscriptionIndex() (map[string][]*Subscription, map[string][]string, error) {
+	index := &p.subscriptionIndex
+
+	index.lock.RLock()
+	repositories, patterns := index.repositories, index.patterns
+	index.lock.RUnlock()
+	if repositories != nil {
+		return repositories, patterns, nil
+	}
+
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	if index.repositories != nil {
+		return index.repositories, index.patterns, nil
+	}
+
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not load subscriptions")
+	}
+
+	index.repositories = subs.Repositories
+	index.patterns = map[string][]string{}
+	for key := range subs.Repositories {
+		if owner, repo := parseOwnerAndRepo(key, ""); isRepositoryPattern(repo) {
+			index.patterns[owner] = append(index.patterns[owner], key)
+		}
+	}
+
+	return index.repositories, index.patterns, nil
+}
+
+// resetSubscriptionIndex drops the in-memory index of this node.
+func (p *Plugin) resetSubscriptionIndex() {
+	p.subscriptionIndex.lock.Lock()
+	defer p.subscriptionIndex.lock.Unlock()
+
+	p.subscriptionIndex.repositories = nil
+	p.subscriptionIndex.patterns = nil
+}
+
+// subscriptionsChanged drops the in-memory index of all nodes after subscriptions were changed.
+func (p *Plugin) subscriptionsChanged() {
+	p.resetSubscriptionIndex()
+
+	err := p.API.PublishPluginClusterEvent(
+		model.PluginClusterEvent{Id: clusterEventSubscriptionsChanged},
+		model.PluginClusterEventSendOptions{SendType: model.PluginClusterEventSendTypeReliable},
+	)
+	if err != nil {
+		p.API.LogWarn("Failed to publish subscriptions change to the cluster", "error", err.Error())
+	}
+}
+
+// OnPluginClusterEvent drops the in-memory subscription or thread link index when they changed on another node.
+func (p *Plugin) OnPluginClusterEvent(c *plugin.Context, ev model.PluginClusterEvent) {
+	switch ev.Id {
+	case clusterEventSubscriptionsChanged:
+		p.resetSubscriptionIndex()
+	case clusterEventThreadLinksChanged:
+		p.resetThreadLinkIndex()
+	}
+}
@@ -0,0 +1,240 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+)
+
+const (
+	subscriptionReposRefreshJobKey = "subscription_repos_refresh_job"
+
+	// subscriptionReposRefreshInterval is how often the repositories of topic and team subscriptions are resolved again.
+	subscriptionReposRefreshInterval = time.Hour
+
+	subscriptionReposPerPage = 100
+)
+
+// subscriptionReposRefreshDelay is how long a refresh triggered by a webhook event waits for further events of the same organization.
+var subscriptionReposRefreshDelay = 30 * time.Second
+
+// resolveSubscriptionRepos returns the lowercase full names of the repositories of owner
+// that match the topic or team of flags, sorted by name.
+func resolveSubscriptionRepos(ctx context.Context, githubClient *github.Client, owner string, flags SubscriptionFlags) ([]string, error) {
+	resolved := []string{}
+
+	switch {
+	case flags.Topic != "":
+		query := fmt.Sprintf("org:%s topic:%s", owner, flags.Topic)
+		opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: subscriptionReposPerPage}}
+		for {
+			result, resp, err := githubClient.Search.Repositories(ctx, query, opt)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not search repositories with topic %s", flags.Topic)
+			}
+			for _, repo := range result.Repositories {
+				resolved = append(resolved, strings.ToLower(repo.GetFullName()))
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	case flags.Team != "":
+		opt := &github.ListOptions{PerPage: subscriptionReposPerPage}
+		for {
+			repos, resp, err := githubClient.Teams.ListTeamReposBySlug(ctx, owner, flags.Team, opt)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not list repositories of team %s", flags.Team)
+			}
+			for _, repo := range repos {
+				resolved = append(resolved, strings.ToLower(repo.GetFullName()))
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+
+	sort.Strings(resolved)
+
+	return resolved, nil
+}
+
+// scheduleSubscriptionReposRefresh schedules refreshSubscriptionRepos to run periodically on one node of the cluster.
+func (p *Plugin) scheduleSubscriptionReposRefresh() error {
+	job, err := cluster.Schedule(p.API, subscriptionReposRefreshJobKey, cluster.MakeWaitForRoundedInterval(subscriptionReposRefreshInterval), func() {
+		p.refreshSubscriptionRepos("")
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not schedule refresh of subscription repositories")
+	}
+
+	p.subscriptionReposRefreshJob = job
+
+	return nil
+}
+
+// refreshSubscriptionRepos resolves the repositories of all topic and team subscriptions again,
+// limited to the subscriptions of owner if it isn't empty.
+func (p *Plugin) refreshSubscriptionRepos(owner string) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions for refresh", "error", err.Error())
+		return
+	}
+
+	ctx := context.Background()
+	for repository, repoSubs := range subs.Repositories {
+		subOwner, repo := parseOwnerAndRepo(repository, "")
+		if repo != "" || (owner != "" && !strings.EqualFold(subOwner, owner)) {
+			continue
+		}
+
+		for _, sub := range repoSubs {
+			if sub.Flags.Topic == "" && sub.Flags.Team == "" {
+				continue
+			}
+			if err := p.refreshSubscriptionReposOf(ctx, repository, sub); err != nil {
+				p.API.LogWarn("Failed to refresh repositories of subscription", "channel_id", sub.ChannelID, "repo", repository, "error", err.Error())
+			}
+		}
+	}
+}
+
+// refreshSubscriptionReposOf resolves the repositories of sub, a subscription of repository, with the GitHub account of its creator.
+func (p *Plugin) refreshSubscriptionReposOf(ctx context.Context, repository string, sub *Subscription) error {
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		return errors.Wrap(apiErr, "could not get GitHub account of creator")
+	}
+
+	owner, _ := parseOwnerAndRepo(repository, "")
+	resolved, err := resolveSubscriptionRepos(ctx, p.githubConnectUser(ctx, info), owner, sub.Flags)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(resolved, ",") == strings.Join(sub.ResolvedRepos, ",") {
+		return nil
+	}
+
+	return p.storeResolvedRepos(repository, sub, resolved)
+}
+
+// storeResolvedRepos updates the resolved repositories of the subscription of the channel of sub to repository,
+// unless it was changed to another topic or team in the meantime.
+func (p *Plugin) storeResolvedRepos(repository string, sub *Subscription, resolved []string) error {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	err := client.KV.SetAtomicWithRetries(getRepoSubscriptionsKey(repository), func(oldValue []byte) (interface{}, error) {
+		repoSubs, err := decodeSubscriptions(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range repoSubs {
+			if s.ChannelID == sub.ChannelID && s.Flags.Topic == sub.Flags.Topic && s.Flags.Team == sub.Flags.Team {
+				s.ResolvedRepos = resolved
+			}
+		}
+
+		return repoSubs, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not store resolved repositories")
+	}
+
+	p.subscriptionsChanged()
+
+	return nil
+}
+
+// subscriptionReposRefreshes debounces the refreshes of the topic and team subscriptions of an organization
+// triggered by webhook events, so that a burst of events causes a single refresh in the background.
+type subscriptionReposRefreshes struct {
+	lock sync.Mutex
+
+	// timers holds the pending refresh of each organization, by lowercase login.
+	timers map[string]*time.Timer
+}
+
+// handleRepositoryEvent refreshes the topic and team subscriptions of the organization of a created or edited repository,
+// so that it is posted to the subscribed channels without waiting for the periodic refresh.
+func (p *Plugin) handleRepositoryEvent(event *github.RepositoryEvent) {
+	action := event.GetAction()
+	if action != actionCreated && action != actionEdited {
+		return
+	}
+
+	if event.GetRepo().GetPrivate() && !p.getConfiguration().EnablePrivateRepo {
+		return
+	}
+
+	p.queueSubscriptionReposRefresh(event.GetRepo().GetOwner().GetLogin())
+}
+
+// handleTeamEvent refreshes the team subscriptions of the organization of a team whose repositories changed.
+func (p *Plugin) handleTeamEvent(event *github.TeamEvent) {
+	action := event.GetAction()
+	if action != actionAddedToRepository && action != actionRemovedFromRepository {
+		return
+	}
+
+	if event.GetRepo().GetPrivate() && !p.getConfiguration().EnablePrivateRepo {
+		return
+	}
+
+	p.queueSubscriptionReposRefresh(event.GetOrg().GetLogin())
+}
+
+// queueSubscriptionReposRefresh refreshes the topic and team subscriptions of owner in the background, once no event
+// has been received for subscriptionReposRefreshDelay.
+func (p *Plugin) queueSubscriptionReposRefresh(owner string) {
+	if owner == "" {
+		return
+	}
+	key := strings.ToLower(owner)
+
+	refreshes := &p.subscriptionReposRefreshes
+	refreshes.lock.Lock()
+	defer refreshes.lock.Unlock()
+
+	if timer, ok := refreshes.timers[key]; ok {
+		timer.Reset(subscriptionReposRefreshDelay)
+		return
+	}
+
+	if refreshes.timers == nil {
+		refreshes.timers = map[string]*time.Timer{}
+	}
+	refreshes.timers[key] = time.AfterFunc(subscriptionReposRefreshDelay, func() {
+		refreshes.lock.Lock()
+		delete(refreshes.timers, key)
+		refreshes.lock.Unlock()
+
+		p.refreshSubscriptionRepos(owner)
+	})
+}
+
+// stopSubscriptionReposRefreshes cancels the pending refreshes queued by queueSubscriptionReposRefresh.
+func (p *Plugin) stopSubscriptionReposRefreshes() {
+	refreshes := &p.subscriptionReposRefreshes
+	refreshes.lock.Lock()
+	defer refreshes.lock.Unlock()
+
+	for key, timer := range refreshes.timers {
+		timer.Stop()
+		delete(refreshes.timers, key)
+	}
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestRefreshSubscriptionRepos(t *testing.T) {
+	defer func(delay time.Duration) { subscriptionReposRefreshDelay = delay }(subscriptionReposRefreshDelay)
+	subscriptionReposRefreshDelay = time.Millisecond
+
+	topicRepos := `{"total_count": 1, "items": [{"full_name": "Mattermost/Payments-API"}]}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/search/repositories", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "org:mattermost topic:team-platform", r.URL.Query().Get("q"))
+		fmt.Fprint(w, topicRepos)
+	})
+	mux.HandleFunc("/api/v3/orgs/mattermost/teams/platform/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+			fmt.Fprint(w, `[{"full_name": "mattermost/mattermost-server"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"full_name": "mattermost/mattermost-webapp"}]`)
+	})
+
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "topic", CreatorID: "user", Repository: "mattermost/", Flags: SubscriptionFlags{Topic: "team-platform"}},
+		{ChannelID: "team", CreatorID: "user", Repository: "mattermost/", Flags: SubscriptionFlags{Team: "platform"}},
+		{ChannelID: "all", CreatorID: "user", Repository: "mattermost/"},
+	})
+	require.NoError(t, p.storeGitHubUserInfo(serveTestGitHub(t, p, nil, mux)))
+
+	channels := func(repo string) []string {
+		var channelIDs []string
+		for _, sub := range p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP(repo)}) {
+			channelIDs = append(channelIDs, sub.ChannelID)
+		}
+		return channelIDs
+	}
+
+	assert.Equal(t, []string{"all"}, channels("mattermost/payments-api"))
+
+	p.refreshSubscriptionRepos("")
+
+	assert.ElementsMatch(t, []string{"all", "topic"}, channels("mattermost/payments-api"))
+	assert.ElementsMatch(t, []string{"all", "team"}, channels("mattermost/mattermost-webapp"))
+	assert.ElementsMatch(t, []string{"all", "team"}, channels("mattermost/mattermost-server"))
+
+	t.Run("repository events refresh the organization", func(t *testing.T) {
+		topicRepos = `{"total_count": 1, "items": [{"full_name": "mattermost/payments-worker"}]}`
+
+		p.handleRepositoryEvent(&github.RepositoryEvent{
+			Action: sToP(actionEdited),
+			Repo:   &github.Repository{FullName: sToP("mattermost/payments-worker"), Owner: &github.User{Login: sToP("mattermost")}},
+		})
+
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"all"}, channels("mattermost/payments-api"))
+		}, time.Second, 10*time.Millisecond)
+		assert.ElementsMatch(t, []string{"all", "topic"}, channels("mattermost/payments-worker"))
+	})
+
+	t.Run("private repositories are ignored unless enabled", func(t *testing.T) {
+		topicRepos = `{"total_count": 1, "items": [{"full_name": "mattermost/secret"}]}`
+
+		p.handleRepositoryEvent(&github.RepositoryEvent{
+			Action: sToP(actionCreated),
+			Repo:   &github.Repository{FullName: sToP("mattermost/secret"), Private: bToP(true), Owner: &github.User{Login: sToP("mattermost")}},
+		})
+
+		p.subscriptionReposRefreshes.lock.Lock()
+		assert.Empty(t, p.subscriptionReposRefreshes.timers)
+		p.subscriptionReposRefreshes.lock.Unlock()
+	})
+
+	t.Run("team events refresh the organization", func(t *testing.T) {
+		p.handleTeamEvent(&github.TeamEvent{
+			Action: sToP(actionAddedToRepository),
+			Org:    &github.Organization{Login: sToP("mattermost")},
+			Repo:   &github.Repository{FullName: sToP("mattermost/secret")},
+		})
+
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"all"}, channels("mattermost/payments-worker"))
+		}, time.Second, 10*time.Millisecond)
+		assert.ElementsMatch(t, []string{"all", "topic"}, channels("mattermost/secret"))
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		client, err := GetGitHubClient(oauth2.Token{AccessToken: "token"}, p.getConfiguration())
+		require.NoError(t, err)
+
+		resolved, err := resolveSubscriptionRepos(context.Background(), client, "mattermost", SubscriptionFlags{Team: "platform"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"mattermost/mattermost-server", "mattermost/mattermost-webapp"}, resolved)
+	})
+}
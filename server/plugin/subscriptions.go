@@ -3,34 +3,88 @@ package plugin
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 
 	"github.com/google/go-github/v41/github"
 	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 )
 
 const (
-	SubscriptionsKey              = "subscriptions"
-	excludeOrgMemberFlag          = "exclude-org-member"
-	excludeOrgReposFlag           = "exclude"
+	// SubscriptionsKey is the legacy key all subscriptions were stored under. See migrateSubscriptions.
+	SubscriptionsKey               = "subscriptions"
+	SubscribedRepositoriesKey      = "subscribedrepositories"
+	repoSubscriptionsKeyPrefix     = "reposubscriptions_"
+	channelSubscriptionsKey        = "_channelsubscriptions"
+	subscriptionsMigrationMutexKey = "subscriptions_migration"
+	excludeOrgMemberFlag           = "exclude-org-member"
+	excludeOrgReposFlag            = "exclude"
+	renderStyleFlag                = "render-style"
+	topicFlag                      = "topic"
+	teamFlag                       = "team"
+	// SubscribedRepoNotificationOff is the legacy key of the global list of excluded repositories.
 	SubscribedRepoNotificationOff = "subscribed-turned-off-notifications"
 )
 
 type SubscriptionFlags struct {
 	ExcludeOrgMembers bool
 	ExcludeOrgRepos   bool
+	// ExcludeRepos are the repositories of an organization subscription that no events are posted for.
+	// Entries are lowercase owner/repo names or glob patterns like owner/sandbox-*.
+	ExcludeRepos []string `json:",omitempty"`
+	RenderStyle  string
+	// Topic and Team limit an organization subscription to the repositories with this topic
+	// or owned by the team with this slug. See refreshSubscriptionRepos.
+	Topic string `json:",omitempty"`
+	Team  string `json:",omitempty"`
 }
 
 func (s *SubscriptionFlags) AddFlag(flag string) {
 	switch flag { // nolint:gocritic // It's expected that more flags get added.
 	case excludeOrgMemberFlag:
 		s.ExcludeOrgMembers = true
+	}
+}
+
+// flagTakesValue reports whether flag expects the next parameter as its value.
+func flagTakesValue(flag string) bool {
+	return flag == renderStyleFlag || flag == excludeOrgReposFlag || flag == topicFlag || flag == teamFlag
+}
+
+// SetFlagValue sets the value of a flag that takes one.
+func (s *SubscriptionFlags) SetFlagValue(flag, value string) error {
+	switch flag { // nolint:gocritic // It's expected that more flags get added.
+	case renderStyleFlag:
+		if !isValidRenderStyle(value) {
+			return errors.Errorf("Invalid value %q for --%s. Accepted values are %q and %q.", value, renderStyleFlag, renderStyleMarkdown, renderStyleAttachment)
+		}
+		s.RenderStyle = value
 	case excludeOrgReposFlag:
-		s.ExcludeOrgRepos = true
+		for _, pattern := range strings.Split(value, ",") {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.Errorf("Invalid pattern %q for --%s.", pattern, excludeOrgReposFlag)
+			}
+			s.ExcludeRepos = append(s.ExcludeRepos, pattern)
+		}
+		s.ExcludeOrgRepos = len(s.ExcludeRepos) > 0
+	case topicFlag:
+		s.Topic = strings.ToLower(strings.TrimSpace(value))
+	case teamFlag:
+		s.Team = strings.ToLower(strings.TrimSpace(value))
 	}
+
+	return nil
 }
 
 func (s SubscriptionFlags) String() string {
@@ -41,7 +95,33 @@ func (s SubscriptionFlags) String() string {
 		flags = append(flags, flag)
 	}
 
-	return strings.Join(flags, ",")
+	if len(s.ExcludeRepos) > 0 {
+		flag := "--" + excludeOrgReposFlag + " " + strings.Join(s.ExcludeRepos, ",")
+		flags = append(flags, flag)
+	}
+
+	if s.RenderStyle != "" {
+		flag := "--" + renderStyleFlag + " " + s.RenderStyle
+		flags = append(flags, flag)
+	}
+
+	if s.Topic != "" {
+		flag := "--" + topicFlag + " " + s.Topic
+		flags = append(flags, flag)
+	}
+
+	if s.Team != "" {
+		flag := "--" + teamFlag + " " + s.Team
+		flags = append(flags, flag)
+	}
+
+	return strings.Join(flags, " ")
+}
+
+// splitSubscriptionFlags splits flags as returned by SubscriptionFlags.String into command parameters.
+// Flags used to be joined by commas, so flags exported that way are split as well.
+func splitSubscriptionFlags(flags string) []string {
+	return strings.Fields(strings.ReplaceAll(flags, ",--", " --"))
 }
 
 type Subscription struct {
@@ -50,6 +130,8 @@ type Subscription struct {
 	Features   string
 	Flags      SubscriptionFlags
 	Repository string
+	// ResolvedRepos are the lowercase full names of the repositories matching the topic or team of the subscription.
+	ResolvedRepos []string `json:",omitempty"`
 }
 
 type Subscriptions struct {
@@ -113,7 +195,74 @@ func (s *Subscription) ExcludeOrgMembers() bool {
 	return s.Flags.ExcludeOrgMembers
 }
 
+// isRepositoryPattern reports whether repo is a glob pattern like payments-* rather than a repository name.
+func isRepositoryPattern(repo string) bool {
+	return strings.ContainsAny(repo, "*?[")
+}
+
+// qualifyExcludeRepos prefixes excluded repositories given without an owner with the subscribed organization.
+func (s *SubscriptionFlags) qualifyExcludeRepos(owner string) {
+	for i, pattern := range s.ExcludeRepos {
+		if !strings.Contains(pattern, "/") {
+			s.ExcludeRepos[i] = fullNameFromOwnerAndRepo(strings.ToLower(owner), pattern)
+		}
+	}
+}
+
+// Excludes reports whether events of the repository with the given full name are excluded from the subscription.
+// Subscriptions by topic or team exclude all repositories not resolved for them.
+func (s *Subscription) Excludes(repo string) bool {
+	repo = strings.ToLower(repo)
+	if s.Flags.Topic != "" || s.Flags.Team != "" {
+		if !SliceContainsString(s.ResolvedRepos, repo) {
+			return true
+		}
+	}
+
+	for _, pattern := range s.Flags.ExcludeRepos {
+		if matched, _ := path.Match(pattern, repo); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, userID, owner, repo, channelID, features string, flags SubscriptionFlags) error {
+	if err := p.validateSubscription(ctx, githubClient, owner, repo, flags); err != nil {
+		return err
+	}
+
+	owner = strings.ToLower(owner)
+	repo = strings.ToLower(repo)
+
+	sub := &Subscription{
+		ChannelID:  channelID,
+		CreatorID:  userID,
+		Features:   features,
+		Repository: fullNameFromOwnerAndRepo(owner, repo),
+		Flags:      flags,
+	}
+
+	if flags.Topic != "" || flags.Team != "" {
+		resolved, err := resolveSubscriptionRepos(ctx, githubClient, owner, flags)
+		if err != nil {
+			p.API.LogWarn("Failed to resolve repositories of subscription", "owner", owner, "error", err.Error())
+			return errors.Errorf("Encountered an error looking up the repositories of %s", owner)
+		}
+		sub.ResolvedRepos = resolved
+	}
+
+	if err := p.AddSubscription(fullNameFromOwnerAndRepo(owner, repo), sub); err != nil {
+		return errors.Wrap(err, "could not add subscription")
+	}
+
+	return nil
+}
+
+// validateSubscription checks that the repository or organization can be subscribed to with the given flags,
+// looking it up with githubClient of the subscriber.
+func (p *Plugin) validateSubscription(ctx context.Context, githubClient *github.Client, owner, repo string, flags SubscriptionFlags) error {
 	if owner == "" {
 		return errors.Errorf("invalid repository")
 	}
@@ -129,9 +278,31 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 		return errors.Errorf("Unable to set --exclude-org-member flag. The GitHub plugin is not locked to a single organization.")
 	}
 
+	if len(flags.ExcludeRepos) > 0 && repo != "" {
+		return errors.New("--exclude feature currently support on organization level.")
+	}
+	if (flags.Topic != "" || flags.Team != "") && repo != "" {
+		return errors.New("--topic and --team are only supported on organization level.")
+	}
+	if flags.Topic != "" && flags.Team != "" {
+		return errors.New("Only one of --topic and --team can be set.")
+	}
+
+	for _, pattern := range flags.ExcludeRepos {
+		if excludeOwner := strings.Split(pattern, "/")[0]; excludeOwner != owner {
+			return errors.Errorf("--exclude repository %s is not of subscribed organization.", pattern)
+		}
+	}
+
+	if isRepositoryPattern(repo) {
+		if _, err := path.Match(repo, ""); err != nil {
+			return errors.Errorf("Invalid repository pattern %s", fullNameFromOwnerAndRepo(owner, repo))
+		}
+	}
+
 	var err error
 
-	if repo == "" {
+	if repo == "" || isRepositoryPattern(repo) {
 		var ghOrg *github.Organization
 		ghOrg, _, err = githubClient.Organizations.Get(ctx, owner)
 		if ghOrg == nil {
@@ -140,6 +311,9 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 			if ghUser == nil {
 				return errors.Errorf("Unknown organization %s", owner)
 			}
+			if flags.Team != "" {
+				return errors.Errorf("--team is only supported for organizations, but %s is a user.", owner)
+			}
 		}
 	} else {
 		var ghRepo *github.Repository
@@ -155,18 +329,6 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 		return errors.Errorf("Encountered an error subscribing to %s", fullNameFromOwnerAndRepo(owner, repo))
 	}
 
-	sub := &Subscription{
-		ChannelID:  channelID,
-		CreatorID:  userID,
-		Features:   features,
-		Repository: fullNameFromOwnerAndRepo(owner, repo),
-		Flags:      flags,
-	}
-
-	if err := p.AddSubscription(fullNameFromOwnerAndRepo(owner, repo), sub); err != nil {
-		return errors.Wrap(err, "could not add subscription")
-	}
-
 	return nil
 }
 
@@ -178,28 +340,141 @@ func (p *Plugin) SubscribeOrg(ctx context.Context, githubClient *github.Client,
 	return p.Subscribe(ctx, githubClient, userID, org, "", channelID, features, flags)
 }
 
-func (p *Plugin) IsNotificationOff(repoName string) bool {
-	repos, err := p.GetExcludedNotificationRepos()
+// GetExcludedNotificationRepos returns the legacy global list of excluded repositories.
+// See migrateExcludedNotificationRepos.
+func (p *Plugin) GetExcludedNotificationRepos() ([]string, error) {
+	var subscriptions []string
+	value, appErr := p.API.KVGet(SubscribedRepoNotificationOff)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get subscriptions from KVStore")
+	}
+	if value == nil {
+		return []string{}, nil
+	}
+	err := json.NewDecoder(bytes.NewReader(value)).Decode(&subscriptions)
 	if err != nil {
-		p.API.LogWarn("Failed to check the disabled notification repo list.", "error", err.Error())
-		return false
+		return nil, errors.Wrap(err, "could not properly decode subscriptions key")
 	}
-	if len(repos) == 0 {
-		return false
+	return subscriptions, nil
+}
+
+// getRepoSubscriptionsKey returns the key of the subscriptions of a repository or organization.
+// The name is hashed to stay within the KV key length limit.
+func getRepoSubscriptionsKey(repo string) string {
+	return fmt.Sprintf("%s%x", repoSubscriptionsKeyPrefix, sha256.Sum256([]byte(repo)))
+}
+
+// decodeSubscriptions decodes the subscriptions stored under a single repository or organization key.
+func decodeSubscriptions(data []byte) ([]*Subscription, error) {
+	var subs []*Subscription
+	if len(data) == 0 {
+		return subs, nil
 	}
-	exist, _ := ItemExists(repos, repoName)
 
-	return exist
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, errors.Wrap(err, "could not properly decode subscriptions")
+	}
+
+	return subs, nil
 }
+
+// decodeStringList decodes a list of names stored as JSON.
+func decodeStringList(data []byte) ([]string, error) {
+	var list []string
+	if len(data) == 0 {
+		return list, nil
+	}
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrap(err, "could not properly decode list")
+	}
+
+	return list, nil
+}
+
+// modifyStringList atomically adds or removes value from the list stored under key.
+// The key is deleted once the list is empty.
+func (p *Plugin) modifyStringList(key, value string, add bool) error {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	return client.KV.SetAtomicWithRetries(key, func(oldValue []byte) (interface{}, error) {
+		list, err := decodeStringList(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		exists, index := ItemExists(list, value)
+		switch {
+		case add && !exists:
+			list = append(list, value)
+		case !add && exists:
+			list = append(list[:index], list[index+1:]...)
+		}
+
+		if len(list) == 0 {
+			return nil, nil
+		}
+
+		return list, nil
+	})
+}
+
+// getRepoSubscriptions returns the subscriptions of a repository, or of an organization
+// if repo has the form "owner/".
+func (p *Plugin) getRepoSubscriptions(repo string) ([]*Subscription, error) {
+	data, appErr := p.API.KVGet(getRepoSubscriptionsKey(repo))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get subscriptions from KVStore")
+	}
+
+	return decodeSubscriptions(data)
+}
+
+// GetSubscriptions returns the subscriptions of all repositories and organizations.
+func (p *Plugin) GetSubscriptions() (*Subscriptions, error) {
+	data, appErr := p.API.KVGet(SubscribedRepositoriesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get subscribed repositories from KVStore")
+	}
+
+	repos, err := decodeStringList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := &Subscriptions{Repositories: map[string][]*Subscription{}}
+	for _, repo := range repos {
+		repoSubs, err := p.getRepoSubscriptions(repo)
+		if err != nil {
+			return nil, err
+		}
+		if len(repoSubs) > 0 {
+			subs.Repositories[repo] = repoSubs
+		}
+	}
+
+	return subs, nil
+}
+
 func (p *Plugin) GetSubscriptionsByChannel(channelID string) ([]*Subscription, error) {
-	var filteredSubs []*Subscription
-	subs, err := p.GetSubscriptions()
+	data, appErr := p.API.KVGet(channelID + channelSubscriptionsKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get channel subscriptions from KVStore")
+	}
+
+	repos, err := decodeStringList(data)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get subscriptions")
 	}
 
-	for repo, v := range subs.Repositories {
-		for _, s := range v {
+	var filteredSubs []*Subscription
+	for _, repo := range repos {
+		repoSubs, err := p.getRepoSubscriptions(repo)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get subscriptions")
+		}
+
+		for _, s := range repoSubs {
 			if s.ChannelID == channelID {
 				// this is needed to be backwards compatible
 				if len(s.Repository) == 0 {
@@ -217,206 +492,301 @@ func (p *Plugin) GetSubscriptionsByChannel(channelID string) ([]*Subscription, e
 	return filteredSubs, nil
 }
 
+// AddSubscription adds sub to the subscriptions of repo, replacing an existing subscription of the same channel.
+// The change is recorded in the subscription history of the channel with the creator of sub as actor.
 func (p *Plugin) AddSubscription(repo string, sub *Subscription) error {
-	subs, err := p.GetSubscriptions()
+	before, err := p.putSubscription(repo, sub)
 	if err != nil {
-		return errors.Wrap(err, "could not get subscriptions")
+		return err
 	}
 
-	repoSubs := subs.Repositories[repo]
-	if repoSubs == nil {
-		repoSubs = []*Subscription{sub}
-	} else {
-		exists := false
+	p.recordSubscriptionChange(sub.CreatorID, sub.ChannelID, repo, before, sub)
+
+	return nil
+}
+
+// putSubscription adds sub to the subscriptions of repo like AddSubscription, without recording the change.
+// It returns the replaced subscription, if any.
+func (p *Plugin) putSubscription(repo string, sub *Subscription) (*Subscription, error) {
+	before, stored, err := p.writeSubscription(repo, sub)
+	// The repository key may already be updated, so changes must be published even if updating the lists fails.
+	if stored {
+		p.subscriptionsChanged()
+	}
+
+	return before, err
+}
+
+// writeSubscription adds sub to the subscriptions of repo like putSubscription, without publishing the change.
+// It reports whether the subscriptions of repo were stored, in which case the caller must call subscriptionsChanged.
+// The subscriptions of each repository are updated atomically, so concurrent changes on other nodes aren't lost.
+func (p *Plugin) writeSubscription(repo string, sub *Subscription) (before *Subscription, stored bool, err error) {
+	client := pluginapi.NewClient(p.API, p.Driver)
+	err = client.KV.SetAtomicWithRetries(getRepoSubscriptionsKey(repo), func(oldValue []byte) (interface{}, error) {
+		repoSubs, err := decodeSubscriptions(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		before = nil
 		for index, s := range repoSubs {
 			if s.ChannelID == sub.ChannelID {
+				before = s
 				repoSubs[index] = sub
-				exists = true
 				break
 			}
 		}
 
-		if !exists {
+		if before == nil {
 			repoSubs = append(repoSubs, sub)
 		}
+
+		return repoSubs, nil
+	})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "could not store subscriptions")
 	}
 
-	subs.Repositories[repo] = repoSubs
+	if err := p.modifyStringList(SubscribedRepositoriesKey, repo, true); err != nil {
+		return nil, true, errors.Wrap(err, "could not store subscribed repositories")
+	}
 
-	err = p.StoreSubscriptions(subs)
-	if err != nil {
-		return errors.Wrap(err, "could not store subscriptions")
+	if err := p.modifyStringList(sub.ChannelID+channelSubscriptionsKey, repo, true); err != nil {
+		return nil, true, errors.Wrap(err, "could not store channel subscriptions")
 	}
 
-	return nil
+	return before, true, nil
 }
 
-func (p *Plugin) GetSubscriptions() (*Subscriptions, error) {
-	var subscriptions *Subscriptions
+func (p *Plugin) GetSubscribedChannelsForRepository(repo *github.Repository) []*Subscription {
+	name := repo.GetFullName()
+	name = strings.ToLower(name)
+	org := strings.Split(name, "/")[0]
 
-	value, appErr := p.API.KVGet(SubscriptionsKey)
-	if appErr != nil {
-		return nil, errors.Wrap(appErr, "could not get subscriptions from KVStore")
+	repositories, patterns, err := p.getSubscriptionIndex()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions", "error", err.Error())
+		return nil
 	}
 
-	if value == nil {
-		return &Subscriptions{Repositories: map[string][]*Subscription{}}, nil
+	// Add subscriptions for the specific repo
+	subsForRepo := []*Subscription{}
+	subsForRepo = append(subsForRepo, repositories[name]...)
+
+	// Add subscriptions for the organization
+	subsForRepo = append(subsForRepo, repositories[fullNameFromOwnerAndRepo(org, "")]...)
+
+	// Add subscriptions for repository patterns of the organization
+	for _, pattern := range patterns[org] {
+		if matched, _ := path.Match(pattern, name); matched {
+			subsForRepo = append(subsForRepo, repositories[pattern]...)
+		}
 	}
 
-	err := json.NewDecoder(bytes.NewReader(value)).Decode(&subscriptions)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not properly decode subscriptions key")
+	if len(subsForRepo) == 0 {
+		return nil
 	}
 
-	return subscriptions, nil
+	subsToReturn := []*Subscription{}
+
+	for _, sub := range subsForRepo {
+		if sub.Excludes(name) {
+			continue
+		}
+		if repo.GetPrivate() && !p.permissionToRepo(sub.CreatorID, name) {
+			continue
+		}
+		subsToReturn = append(subsToReturn, sub)
+	}
+
+	return subsToReturn
 }
 
-func (p *Plugin) StoreSubscriptions(s *Subscriptions) error {
-	b, err := json.Marshal(s)
+// Unsubscribe removes the subscription of channelID to repo. The change is recorded in the
+// subscription history of the channel with userID as actor.
+func (p *Plugin) Unsubscribe(channelID, repo, userID string) error {
+	removed, err := p.removeSubscription(channelID, repo)
 	if err != nil {
-		return errors.Wrap(err, "error while converting subscriptions map to json")
+		return err
 	}
 
-	if appErr := p.API.KVSet(SubscriptionsKey, b); appErr != nil {
-		return errors.Wrap(appErr, "could not store subscriptions in KV store")
+	if removed != nil {
+		p.recordSubscriptionChange(userID, channelID, removed.Repository, removed, nil)
 	}
 
 	return nil
 }
 
-func (p *Plugin) GetExcludedNotificationRepos() ([]string, error) {
-	var subscriptions []string
-	value, appErr := p.API.KVGet(SubscribedRepoNotificationOff)
-	if appErr != nil {
-		return nil, errors.Wrap(appErr, "could not get subscriptions from KVStore")
-	}
-	if value == nil {
-		return []string{}, nil
+// removeSubscription removes the subscription of channelID to repo like Unsubscribe, without recording the change.
+// It returns the removed subscription, or nil if the channel wasn't subscribed.
+func (p *Plugin) removeSubscription(channelID string, repo string) (*Subscription, error) {
+	owner, repo := parseOwnerAndRepo(repo, p.getBaseURL())
+	if owner == "" && repo == "" {
+		return nil, errors.New("invalid repository")
 	}
-	err := json.NewDecoder(bytes.NewReader(value)).Decode(&subscriptions)
+
+	owner = strings.ToLower(owner)
+	repo = strings.ToLower(repo)
+
+	repoWithOwner := fmt.Sprintf("%s/%s", owner, repo)
+
+	var removed *Subscription
+	empty := false
+	client := pluginapi.NewClient(p.API, p.Driver)
+	err := client.KV.SetAtomicWithRetries(getRepoSubscriptionsKey(repoWithOwner), func(oldValue []byte) (interface{}, error) {
+		repoSubs, err := decodeSubscriptions(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		removed = nil
+		for index, sub := range repoSubs {
+			if sub.ChannelID == channelID {
+				repoSubs = append(repoSubs[:index], repoSubs[index+1:]...)
+				removed = sub
+				break
+			}
+		}
+
+		empty = len(repoSubs) == 0
+		if empty {
+			return nil, nil
+		}
+
+		return repoSubs, nil
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "could not properly decode subscriptions key")
+		return nil, errors.Wrap(err, "could not store subscriptions")
 	}
-	return subscriptions, nil
-}
 
-func (p *Plugin) StoreExcludedNotificationRepo(s string) error {
-	var repoNames, err = p.GetExcludedNotificationRepos()
-	if err != nil {
-		return errors.Wrap(err, "error while getting previous value of key")
+	if removed == nil {
+		return nil, nil
 	}
-	isDer, _ := ItemExists(repoNames, s)
-	if len(repoNames) > 0 && !isDer {
-		repoNames = append(repoNames, s)
-	} else if len(repoNames) == 0 {
-		repoNames = append(repoNames, s)
+	// this is needed to be backwards compatible
+	if removed.Repository == "" {
+		removed.Repository = repoWithOwner
 	}
-	b, err := json.Marshal(repoNames)
-	if err != nil {
-		return errors.Wrap(err, "error while converting subscriptions map to json")
+
+	// The repository key is already updated, so changes must be published even if updating the lists fails.
+	defer p.subscriptionsChanged()
+
+	if empty {
+		if err := p.modifyStringList(SubscribedRepositoriesKey, repoWithOwner, false); err != nil {
+			return nil, errors.Wrap(err, "could not store subscribed repositories")
+		}
 	}
 
-	if appErr := p.API.KVSet(SubscribedRepoNotificationOff, b); appErr != nil {
-		return errors.Wrap(appErr, "could not store subscriptions in KV store")
+	if err := p.modifyStringList(channelID+channelSubscriptionsKey, repoWithOwner, false); err != nil {
+		return nil, errors.Wrap(err, "could not store channel subscriptions")
 	}
 
-	return nil
+	return removed, nil
 }
-func (p *Plugin) EnableNotificationTurnedOffRepo(s string) error {
-	var repoNames, err = p.GetExcludedNotificationRepos()
+
+// migrateSubscriptions migrates subscriptions stored by previous versions of the plugin.
+// Only one node of the cluster migrates at a time.
+func (p *Plugin) migrateSubscriptions() error {
+	mutex, err := cluster.NewMutex(p.API, subscriptionsMigrationMutexKey)
 	if err != nil {
-		return errors.Wrap(err, "error while getting previous value of key")
+		return errors.Wrap(err, "could not create subscriptions migration mutex")
 	}
-	if len(repoNames) > 0 {
-		exists, index := ItemExists(repoNames, s)
-		if exists {
-			repoNames = append(repoNames[:index], repoNames[index+1:]...)
-			b, err := json.Marshal(repoNames)
-			if err != nil {
-				return errors.Wrap(err, "error while converting subscriptions map to json")
-			}
+	mutex.Lock()
+	defer mutex.Unlock()
 
-			if appErr := p.API.KVSet(SubscribedRepoNotificationOff, b); appErr != nil {
-				return errors.Wrap(appErr, "could not store subscriptions in KV store")
-			}
+	// Migrated subscriptions are published once at the end rather than once per subscription.
+	changed := false
+	defer func() {
+		if changed {
+			p.subscriptionsChanged()
 		}
-	}
+	}()
 
-	return nil
-}
-func (p *Plugin) GetSubscribedChannelsForRepository(repo *github.Repository) []*Subscription {
-	name := repo.GetFullName()
-	name = strings.ToLower(name)
-	org := strings.Split(name, "/")[0]
-	subs, err := p.GetSubscriptions()
-	if err != nil {
-		return nil
+	if err := p.migrateLegacySubscriptions(&changed); err != nil {
+		return err
 	}
 
-	// Add subscriptions for the specific repo
-	subsForRepo := []*Subscription{}
-	if subs.Repositories[name] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[name]...)
-	}
+	return p.migrateExcludedNotificationRepos(&changed)
+}
 
-	// Add subscriptions for the organization
-	orgKey := fullNameFromOwnerAndRepo(org, "")
-	if subs.Repositories[orgKey] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[orgKey]...)
+// migrateLegacySubscriptions moves subscriptions from the legacy single key into per-repository keys.
+// The legacy key is only deleted once all subscriptions are migrated, so an interrupted
+// migration is resumed on the next activation. changed is set once any subscription is stored.
+func (p *Plugin) migrateLegacySubscriptions(changed *bool) error {
+	value, appErr := p.API.KVGet(SubscriptionsKey)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get legacy subscriptions from KVStore")
 	}
-
-	if len(subsForRepo) == 0 {
+	if value == nil {
 		return nil
 	}
 
-	subsToReturn := []*Subscription{}
+	var legacy *Subscriptions
+	if err := json.Unmarshal(value, &legacy); err != nil {
+		return errors.Wrap(err, "could not properly decode legacy subscriptions")
+	}
 
-	for _, sub := range subsForRepo {
-		if repo.GetPrivate() && !p.permissionToRepo(sub.CreatorID, name) {
-			continue
+	count := 0
+	for repo, repoSubs := range legacy.Repositories {
+		for _, sub := range repoSubs {
+			_, stored, err := p.writeSubscription(repo, sub)
+			*changed = *changed || stored
+			if err != nil {
+				return errors.Wrapf(err, "could not migrate subscription of %s", repo)
+			}
+			count++
 		}
-		subsToReturn = append(subsToReturn, sub)
 	}
 
-	return subsToReturn
-}
-
-func (p *Plugin) Unsubscribe(channelID string, repo string) error {
-	owner, repo := parseOwnerAndRepo(repo, p.getBaseURL())
-	if owner == "" && repo == "" {
-		return errors.New("invalid repository")
+	if appErr := p.API.KVDelete(SubscriptionsKey); appErr != nil {
+		return errors.Wrap(appErr, "could not delete legacy subscriptions")
 	}
 
-	owner = strings.ToLower(owner)
-	repo = strings.ToLower(repo)
+	p.API.LogInfo("Migrated subscriptions to per-repository keys", "count", count)
 
-	repoWithOwner := fmt.Sprintf("%s/%s", owner, repo)
+	return nil
+}
 
-	subs, err := p.GetSubscriptions()
+// migrateExcludedNotificationRepos moves the legacy global list of excluded repositories into the
+// exclusions of the organization subscriptions. The global list applied to every channel, so each
+// excluded repository is added to all subscriptions of its organization. changed is set once any subscription is stored.
+func (p *Plugin) migrateExcludedNotificationRepos(changed *bool) error {
+	excluded, err := p.GetExcludedNotificationRepos()
 	if err != nil {
-		return errors.Wrap(err, "could not get subscriptions")
+		return err
 	}
-
-	repoSubs := subs.Repositories[repoWithOwner]
-	if repoSubs == nil {
+	if len(excluded) == 0 {
 		return nil
 	}
 
-	removed := false
-	for index, sub := range repoSubs {
-		if sub.ChannelID == channelID {
-			repoSubs = append(repoSubs[:index], repoSubs[index+1:]...)
-			removed = true
-			break
+	for _, repo := range excluded {
+		repo = strings.ToLower(strings.TrimSpace(repo))
+		owner, _ := parseOwnerAndRepo(repo, "")
+		orgKey := fullNameFromOwnerAndRepo(owner, "")
+
+		orgSubs, err := p.getRepoSubscriptions(orgKey)
+		if err != nil {
+			return err
 		}
-	}
 
-	if removed {
-		subs.Repositories[repoWithOwner] = repoSubs
-		if err := p.StoreSubscriptions(subs); err != nil {
-			return errors.Wrap(err, "could not store subscriptions")
+		for _, sub := range orgSubs {
+			if exists, _ := ItemExists(sub.Flags.ExcludeRepos, repo); exists {
+				continue
+			}
+			sub.Flags.ExcludeRepos = append(sub.Flags.ExcludeRepos, repo)
+			sub.Flags.ExcludeOrgRepos = true
+			_, stored, err := p.writeSubscription(orgKey, sub)
+			*changed = *changed || stored
+			if err != nil {
+				return errors.Wrapf(err, "could not migrate exclusion of %s", repo)
+			}
 		}
 	}
 
+	if appErr := p.API.KVDelete(SubscribedRepoNotificationOff); appErr != nil {
+		return errors.Wrap(appErr, "could not delete legacy excluded repositories")
+	}
+
+	p.API.LogInfo("Migrated excluded repositories to organization subscriptions", "count", len(excluded))
+
 	return nil
 }
@@ -0,0 +1,443 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	subscriptionsExportVersion = 1
+
+	exportFormatJSON = "json"
+	exportFormatYAML = "yaml"
+
+	exportAllFlag    = "all"
+	exportFormatFlag = "format"
+	importApplyFlag  = "apply"
+
+	importStatusAdd       = "add"
+	importStatusUpdate    = "update"
+	importStatusUnchanged = "unchanged"
+	importStatusError     = "error"
+)
+
+// SubscriptionsExport is the file format of exported subscriptions.
+// Channels are referenced by team and channel name, so that an export can be imported on another server.
+type SubscriptionsExport struct {
+	Version       int                     `json:"version" yaml:"version"`
+	Subscriptions []*ExportedSubscription `json:"subscriptions" yaml:"subscriptions"`
+}
+
+// ExportedSubscription is a single subscription of SubscriptionsExport.
+// Features and flags use the same syntax as /github subscriptions add.
+type ExportedSubscription struct {
+	Team       string `json:"team,omitempty" yaml:"team,omitempty"`
+	Channel    string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Repository string `json:"repository" yaml:"repository"`
+	Features   string `json:"features" yaml:"features"`
+	Flags      string `json:"flags,omitempty" yaml:"flags,omitempty"`
+}
+
+// importedSubscription is the outcome of validating an ExportedSubscription against the current subscriptions.
+type importedSubscription struct {
+	Entry        *ExportedSubscription
+	ChannelID    string
+	Subscription *Subscription
+	// Existing is the subscription an update replaces.
+	Existing *Subscription
+	Status   string
+	Reason   string
+}
+
+// marshalSubscriptionsExport encodes export in the given format.
+func marshalSubscriptionsExport(export *SubscriptionsExport, format string) ([]byte, error) {
+	switch format {
+	case exportFormatJSON:
+		return json.MarshalIndent(export, "", "  ")
+	case exportFormatYAML:
+		return yaml.Marshal(export)
+	default:
+		return nil, errors.Errorf("unknown format %s, must be %s or %s", format, exportFormatJSON, exportFormatYAML)
+	}
+}
+
+// unmarshalSubscriptionsExport decodes an export in either format. JSON is valid YAML, so both are decoded as YAML.
+func unmarshalSubscriptionsExport(data []byte) (*SubscriptionsExport, error) {
+	export := &SubscriptionsExport{}
+	if err := yaml.Unmarshal(data, export); err != nil {
+		return nil, errors.Wrap(err, "could not parse subscriptions file")
+	}
+
+	if export.Version != subscriptionsExportVersion {
+		return nil, errors.Errorf("unsupported subscriptions file version %d", export.Version)
+	}
+
+	return export, nil
+}
+
+// exportSubscription converts sub into its exported form. Channel names are only looked up once per channel.
+func (p *Plugin) exportSubscription(sub *Subscription, channels map[string]*ExportedSubscription) *ExportedSubscription {
+	location, ok := channels[sub.ChannelID]
+	if !ok {
+		location = &ExportedSubscription{Channel: sub.ChannelID}
+		if channel, appErr := p.API.GetChannel(sub.ChannelID); appErr == nil {
+			location.Channel = channel.Name
+			if team, appErr := p.API.GetTeam(channel.TeamId); channel.TeamId != "" && appErr == nil {
+				location.Team = team.Name
+			}
+		}
+		channels[sub.ChannelID] = location
+	}
+
+	return &ExportedSubscription{
+		Team:       location.Team,
+		Channel:    location.Channel,
+		Repository: strings.Trim(sub.Repository, "/"),
+		Features:   sub.Features,
+		Flags:      sub.Flags.String(),
+	}
+}
+
+func (p *Plugin) handleSubscriptionsExport(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	all := false
+	format := exportFormatJSON
+	for i := 0; i < len(parameters); i++ {
+		switch parameters[i] {
+		case "--" + exportAllFlag:
+			all = true
+		case "--" + exportFormatFlag:
+			if i+1 == len(parameters) {
+				return fmt.Sprintf("Please specify a value for --%s.", exportFormatFlag)
+			}
+			i++
+			format = strings.ToLower(parameters[i])
+			if format != exportFormatJSON && format != exportFormatYAML {
+				return fmt.Sprintf("Unknown format %s. Supported formats are %s and %s.", format, exportFormatJSON, exportFormatYAML)
+			}
+		default:
+			return fmt.Sprintf("Unknown parameter %s.", parameters[i])
+		}
+	}
+
+	var subs []*Subscription
+	if all {
+		isSysAdmin, err := p.isAuthorizedSysAdmin(args.UserId)
+		if err != nil {
+			p.API.LogWarn("Failed to check if user is System Admin", "error", err.Error())
+			return "Error checking user's permissions"
+		}
+		if !isSysAdmin {
+			return "Only System Admins are allowed to export the subscriptions of all channels."
+		}
+
+		allSubs, err := p.GetSubscriptions()
+		if err != nil {
+			return err.Error()
+		}
+		for _, repoSubs := range allSubs.Repositories {
+			subs = append(subs, repoSubs...)
+		}
+	} else {
+		var err error
+		subs, err = p.GetSubscriptionsByChannel(args.ChannelId)
+		if err != nil {
+			return err.Error()
+		}
+	}
+
+	if len(subs) == 0 {
+		return "There are no subscriptions to export."
+	}
+
+	export := &SubscriptionsExport{Version: subscriptionsExportVersion}
+	channels := map[string]*ExportedSubscription{}
+	for _, sub := range subs {
+		export.Subscriptions = append(export.Subscriptions, p.exportSubscription(sub, channels))
+	}
+	sort.Slice(export.Subscriptions, func(i, j int) bool {
+		a, b := export.Subscriptions[i], export.Subscriptions[j]
+		if a.Team+"/"+a.Channel != b.Team+"/"+b.Channel {
+			return a.Team+"/"+a.Channel < b.Team+"/"+b.Channel
+		}
+		return a.Repository < b.Repository
+	})
+
+	data, err := marshalSubscriptionsExport(export, format)
+	if err != nil {
+		return err.Error()
+	}
+
+	// Ephemeral posts can't carry files, so the export is sent as a direct message.
+	channel, appErr := p.API.GetDirectChannel(args.UserId, p.BotUserID)
+	if appErr != nil {
+		p.API.LogWarn("Couldn't get bot's DM channel", "userID", args.UserId, "error", appErr.Error())
+		return "Failed to export the subscriptions."
+	}
+
+	filename := fmt.Sprintf("github-subscriptions-%s.%s", time.Now().Format("2006-01-02"), format)
+	fileInfo, appErr := p.API.UploadFile(data, channel.Id, filename)
+	if appErr != nil {
+		p.API.LogWarn("Failed to upload subscriptions export", "error", appErr.Error())
+		return "Failed to export the subscriptions."
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channel.Id,
+		Message:   fmt.Sprintf("Exported %d subscription(s). Use `/github subscriptions import <link to this post>` to import them.", len(export.Subscriptions)),
+		FileIds:   []string{fileInfo.Id},
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscriptions export", "error", appErr.Error())
+		return "Failed to export the subscriptions."
+	}
+
+	return fmt.Sprintf("Exported %d subscription(s). The file was sent to you in a direct message.", len(export.Subscriptions))
+}
+
+// readSubscriptionsFile reads the subscriptions file attached to the post the given permalink or post ID refers to.
+func (p *Plugin) readSubscriptionsFile(userID, ref string) (*SubscriptionsExport, error) {
+	postID := ref[strings.LastIndex(ref, "/")+1:]
+	if !model.IsValidId(postID) {
+		return nil, errors.Errorf("%s is not a link to a post.", ref)
+	}
+
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil || !p.API.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		return nil, errors.New("The post could not be found.")
+	}
+	if len(post.FileIds) == 0 {
+		return nil, errors.New("The post doesn't have a file attached.")
+	}
+
+	fileInfo, appErr := p.API.GetFileInfo(post.FileIds[0])
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get file info")
+	}
+	switch strings.ToLower(filepath.Ext(fileInfo.Name)) {
+	case ".json", ".yaml", ".yml":
+	default:
+		return nil, errors.Errorf("%s is not a JSON or YAML file.", fileInfo.Name)
+	}
+
+	data, appErr := p.API.GetFile(fileInfo.Id)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not read file")
+	}
+
+	return unmarshalSubscriptionsExport(data)
+}
+
+// resolveImportChannel returns the channel an imported subscription targets. Entries without a channel,
+// or naming the current channel, target the current channel. Only System Admins may target other channels.
+func (p *Plugin) resolveImportChannel(entry *ExportedSubscription, current *model.Channel, currentTeam string, isSysAdmin bool) (string, error) {
+	if entry.Channel == "" || (entry.Channel == current.Name && entry.Team == currentTeam) {
+		return current.Id, nil
+	}
+
+	if !isSysAdmin {
+		return "", errors.New("only System Admins can import subscriptions of other channels")
+	}
+
+	if entry.Team == "" {
+		return "", errors.Errorf("channel %s has no team", entry.Channel)
+	}
+
+	team, appErr := p.API.GetTeamByName(entry.Team)
+	if appErr != nil {
+		return "", errors.Errorf("unknown team %s", entry.Team)
+	}
+
+	channel, appErr := p.API.GetChannelByName(team.Id, entry.Channel, false)
+	if appErr != nil {
+		return "", errors.Errorf("unknown channel %s in team %s", entry.Channel, entry.Team)
+	}
+
+	return channel.Id, nil
+}
+
+// diffImportedSubscription compares an imported subscription with the existing subscriptions of its channel.
+// It also returns the existing subscription of the same repository, if any.
+func diffImportedSubscription(sub *Subscription, existing []*Subscription) (string, *Subscription) {
+	for _, s := range existing {
+		if s.Repository != sub.Repository {
+			continue
+		}
+		if s.Features == sub.Features && s.Flags.String() == sub.Flags.String() {
+			return importStatusUnchanged, s
+		}
+		return importStatusUpdate, s
+	}
+
+	return importStatusAdd, nil
+}
+
+// formatSubscriptionOptions returns features and flags as passed to /github subscriptions add.
+func formatSubscriptionOptions(features, flags string) string {
+	if flags == "" {
+		return features
+	}
+	return features + " " + flags
+}
+
+// validateImportedSubscriptions validates each entry of export with githubClient of the importing user
+// and compares it with the existing subscriptions.
+func (p *Plugin) validateImportedSubscriptions(ctx context.Context, githubClient *github.Client, args *model.CommandArgs, export *SubscriptionsExport, isSysAdmin bool) ([]*importedSubscription, error) {
+	current, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get channel")
+	}
+	currentTeam := ""
+	if team, appErr := p.API.GetTeam(current.TeamId); current.TeamId != "" && appErr == nil {
+		currentTeam = team.Name
+	}
+
+	existing := map[string][]*Subscription{}
+	results := make([]*importedSubscription, 0, len(export.Subscriptions))
+	for _, entry := range export.Subscriptions {
+		result := &importedSubscription{Entry: entry, Status: importStatusError}
+		results = append(results, result)
+
+		channelID, err := p.resolveImportChannel(entry, current, currentTeam, isSysAdmin)
+		if err != nil {
+			result.Reason = err.Error()
+			continue
+		}
+		result.ChannelID = channelID
+
+		features, flags, err := parseSubscriptionOptions(append([]string{entry.Features}, splitSubscriptionFlags(entry.Flags)...))
+		if err != nil {
+			result.Reason = err.Error()
+			continue
+		}
+
+		owner, repo := parseOwnerAndRepo(entry.Repository, p.getBaseURL())
+		if repo == "" {
+			flags.qualifyExcludeRepos(owner)
+		}
+		if err = p.validateSubscription(ctx, githubClient, owner, repo, flags); err != nil {
+			result.Reason = err.Error()
+			continue
+		}
+
+		result.Subscription = &Subscription{
+			ChannelID:  channelID,
+			CreatorID:  args.UserId,
+			Features:   features,
+			Repository: fullNameFromOwnerAndRepo(strings.ToLower(owner), strings.ToLower(repo)),
+			Flags:      flags,
+		}
+
+		if repo == "" && (flags.Topic != "" || flags.Team != "") {
+			result.Subscription.ResolvedRepos, err = resolveSubscriptionRepos(ctx, githubClient, strings.ToLower(owner), flags)
+			if err != nil {
+				p.API.LogWarn("Failed to resolve repositories of imported subscription", "owner", owner, "error", err.Error())
+				result.Reason = fmt.Sprintf("Encountered an error looking up the repositories of %s", owner)
+				continue
+			}
+		}
+
+		if _, ok := existing[channelID]; !ok {
+			existing[channelID], err = p.GetSubscriptionsByChannel(channelID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.Status, result.Existing = diffImportedSubscription(result.Subscription, existing[channelID])
+	}
+
+	return results, nil
+}
+
+func (p *Plugin) handleSubscriptionsImport(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	apply := false
+	ref := ""
+	for _, parameter := range parameters {
+		switch {
+		case parameter == "--"+importApplyFlag:
+			apply = true
+		case ref == "" && !isFlag(parameter):
+			ref = parameter
+		default:
+			return fmt.Sprintf("Unknown parameter %s.", parameter)
+		}
+	}
+	if ref == "" {
+		return "Please specify a link to a post with a subscriptions file attached."
+	}
+
+	export, err := p.readSubscriptionsFile(args.UserId, ref)
+	if err != nil {
+		return err.Error()
+	}
+
+	isSysAdmin, err := p.isAuthorizedSysAdmin(args.UserId)
+	if err != nil {
+		p.API.LogWarn("Failed to check if user is System Admin", "error", err.Error())
+		return "Error checking user's permissions"
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+
+	results, err := p.validateImportedSubscriptions(ctx, githubClient, args, export, isSysAdmin)
+	if err != nil {
+		p.API.LogWarn("Failed to validate imported subscriptions", "error", err.Error())
+		return "Failed to import the subscriptions."
+	}
+
+	txt := "### Subscriptions import (dry run)\n"
+	if apply {
+		txt = "### Subscriptions import\n"
+	}
+	counts := map[string]int{}
+	for _, result := range results {
+		entry := result.Entry
+		location := "this channel"
+		if result.ChannelID != args.ChannelId && entry.Channel != "" {
+			location = fmt.Sprintf("~%s (%s)", entry.Channel, entry.Team)
+		}
+
+		if apply && (result.Status == importStatusAdd || result.Status == importStatusUpdate) {
+			if err := p.AddSubscription(result.Subscription.Repository, result.Subscription); err != nil {
+				p.API.LogWarn("Failed to import subscription", "repo", result.Subscription.Repository, "error", err.Error())
+				result.Status = importStatusError
+				result.Reason = "could not store subscription"
+			}
+		}
+		counts[result.Status]++
+
+		options := formatSubscriptionOptions(entry.Features, entry.Flags)
+		if result.Status == importStatusUpdate {
+			options = formatSubscriptionOptions(result.Existing.Features, result.Existing.Flags.String()) + " → " +
+				formatSubscriptionOptions(result.Subscription.Features, result.Subscription.Flags.String())
+		}
+		txt += fmt.Sprintf("* **%s** `%s` - %s", result.Status, strings.Trim(entry.Repository, "/"), options)
+		txt += " in " + location
+		if result.Reason != "" {
+			txt += ": " + result.Reason
+		}
+		txt += "\n"
+	}
+
+	if apply {
+		txt += fmt.Sprintf("\n%d added, %d updated, %d unchanged, %d with errors.",
+			counts[importStatusAdd], counts[importStatusUpdate], counts[importStatusUnchanged], counts[importStatusError])
+	} else {
+		txt += fmt.Sprintf("\n%d to add, %d to update, %d unchanged, %d with errors. Run the command again with `--%s` to apply the changes.",
+			counts[importStatusAdd], counts[importStatusUpdate], counts[importStatusUnchanged], counts[importStatusError], importApplyFlag)
+	}
+
+	return txt
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionsExportImport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/mattermost", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"login": "mattermost"}`)
+	})
+	mux.HandleFunc("/api/v3/repos/mattermost/mattermost-server", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name": "mattermost/mattermost-server"}`)
+	})
+	mux.HandleFunc("/api/v3/search/repositories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count": 1, "items": [{"full_name": "mattermost/payments-api"}]}`)
+	})
+
+	p := pluginWithMockedSubs(nil)
+	api := p.API.(*plugintest.API)
+	api.On("GetChannel", "channel").Return(&model.Channel{Id: "channel", Name: "town-square", TeamId: "team"}, nil)
+	api.On("GetTeam", "team").Return(&model.Team{Id: "team", Name: "main"}, nil)
+	userInfo := serveTestGitHub(t, p, &Configuration{GitHubOrg: "mattermost"}, mux)
+	githubClient := p.githubConnectUser(context.Background(), userInfo)
+	args := &model.CommandArgs{UserId: "user", ChannelId: "channel"}
+
+	subs := []*Subscription{
+		{
+			ChannelID:  "channel",
+			CreatorID:  "user",
+			Repository: "mattermost/",
+			Features:   "pulls,issues",
+			Flags: SubscriptionFlags{
+				ExcludeOrgMembers: true,
+				ExcludeOrgRepos:   true,
+				ExcludeRepos:      []string{"mattermost/sandbox-*", "mattermost/legacy"},
+				RenderStyle:       renderStyleAttachment,
+				Topic:             "platform",
+			},
+			ResolvedRepos: []string{"mattermost/payments-api"},
+		},
+		{
+			ChannelID:  "channel",
+			CreatorID:  "user",
+			Repository: "mattermost/mattermost-server",
+			Features:   `pulls,issues,label:"Help Wanted"`,
+			Flags:      SubscriptionFlags{ExcludeOrgMembers: true, RenderStyle: renderStyleMarkdown},
+		},
+	}
+
+	for _, format := range []string{exportFormatJSON, exportFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			export := &SubscriptionsExport{Version: subscriptionsExportVersion}
+			channels := map[string]*ExportedSubscription{}
+			for _, sub := range subs {
+				export.Subscriptions = append(export.Subscriptions, p.exportSubscription(sub, channels))
+			}
+
+			data, err := marshalSubscriptionsExport(export, format)
+			require.NoError(t, err)
+			decoded, err := unmarshalSubscriptionsExport(data)
+			require.NoError(t, err)
+			assert.Equal(t, export, decoded)
+
+			results, err := p.validateImportedSubscriptions(context.Background(), githubClient, args, decoded, false)
+			require.NoError(t, err)
+			require.Len(t, results, len(subs))
+			for i, result := range results {
+				assert.Empty(t, result.Reason)
+				assert.Equal(t, importStatusAdd, result.Status)
+				assert.Equal(t, subs[i], result.Subscription)
+			}
+		})
+	}
+
+	t.Run("comma separated flags", func(t *testing.T) {
+		export := &SubscriptionsExport{
+			Version: subscriptionsExportVersion,
+			Subscriptions: []*ExportedSubscription{
+				{Repository: "mattermost/mattermost-server", Features: "pulls", Flags: "--exclude-org-member,--render-style markdown"},
+			},
+		}
+
+		results, err := p.validateImportedSubscriptions(context.Background(), githubClient, args, export, false)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Empty(t, results[0].Reason)
+		assert.Equal(t, SubscriptionFlags{ExcludeOrgMembers: true, RenderStyle: renderStyleMarkdown}, results[0].Subscription.Flags)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := unmarshalSubscriptionsExport([]byte(`{"version": 2, "subscriptions": []}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestDiffImportedSubscription(t *testing.T) {
+	existing := []*Subscription{
+		{ChannelID: "1", Repository: "mattermost/mattermost-server", Features: "pulls"},
+		{ChannelID: "1", Repository: "mattermost/", Features: "pulls", Flags: SubscriptionFlags{ExcludeRepos: []string{"mattermost/sandbox"}, ExcludeOrgRepos: true}},
+	}
+
+	status, previous := diffImportedSubscription(&Subscription{Repository: "mattermost/mattermost-server", Features: "pulls"}, existing)
+	assert.Equal(t, importStatusUnchanged, status)
+	assert.Equal(t, existing[0], previous)
+
+	status, previous = diffImportedSubscription(&Subscription{Repository: "mattermost/mattermost-server", Features: "issues"}, existing)
+	assert.Equal(t, importStatusUpdate, status)
+	assert.Equal(t, existing[0], previous)
+
+	status, previous = diffImportedSubscription(&Subscription{Repository: "mattermost/", Features: "pulls"}, existing)
+	assert.Equal(t, importStatusUpdate, status)
+	assert.Equal(t, "pulls --exclude mattermost/sandbox", formatSubscriptionOptions(previous.Features, previous.Flags.String()))
+
+	status, previous = diffImportedSubscription(&Subscription{Repository: "mattermost/mattermost-webapp", Features: "pulls"}, existing)
+	assert.Equal(t, importStatusAdd, status)
+	assert.Nil(t, previous)
+}
+
+func TestResolveImportChannel(t *testing.T) {
+	api := &plugintest.API{}
+	p := NewPlugin()
+	p.SetAPI(api)
+
+	current := &model.Channel{Id: "current", Name: "town-square", TeamId: "team"}
+	api.On("GetTeamByName", "engineering").Return(&model.Team{Id: "engineering-id", Name: "engineering"}, nil)
+	api.On("GetChannelByName", "engineering-id", "server", false).Return(&model.Channel{Id: "server-id"}, nil)
+
+	channelID, err := p.resolveImportChannel(&ExportedSubscription{Repository: "mattermost/mattermost-server"}, current, "main", false)
+	require.NoError(t, err)
+	assert.Equal(t, "current", channelID)
+
+	channelID, err = p.resolveImportChannel(&ExportedSubscription{Team: "main", Channel: "town-square"}, current, "main", false)
+	require.NoError(t, err)
+	assert.Equal(t, "current", channelID)
+
+	_, err = p.resolveImportChannel(&ExportedSubscription{Team: "engineering", Channel: "server"}, current, "main", false)
+	assert.Error(t, err)
+
+	channelID, err = p.resolveImportChannel(&ExportedSubscription{Team: "engineering", Channel: "server"}, current, "main", true)
+	require.NoError(t, err)
+	assert.Equal(t, "server-id", channelID)
+}
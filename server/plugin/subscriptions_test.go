@@ -1,11 +1,16 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func CheckError(t *testing.T, wantErr bool, err error) {
@@ -20,12 +25,14 @@ func CheckError(t *testing.T, wantErr bool, err error) {
 func pluginWithMockedSubs(subscriptions []*Subscription) *Plugin {
 	p := NewPlugin()
 	mockPluginAPI := &plugintest.API{}
-
-	subs := Subscriptions{Repositories: map[string][]*Subscription{}}
-	subs.Repositories[""] = subscriptions
-	jsn, _ := json.Marshal(subs)
-	mockPluginAPI.On("KVGet", SubscriptionsKey).Return(jsn, nil)
+	mockKVStore(mockPluginAPI)
+	mockPluginAPI.On("PublishPluginClusterEvent", mock.Anything, mock.Anything).Return(nil)
 	p.SetAPI(mockPluginAPI)
+
+	for _, sub := range subscriptions {
+		_, _ = p.putSubscription(sub.Repository, sub)
+	}
+
 	return p
 }
 
@@ -114,3 +121,215 @@ func TestPlugin_GetSubscriptionsByChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestAddSubscriptionAndUnsubscribe(t *testing.T) {
+	p := pluginWithMockedSubs(nil)
+	p.setConfiguration(&Configuration{})
+
+	require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "1", Repository: "mattermost/mattermost-server", Features: "pulls"}))
+	require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "2", Repository: "mattermost/mattermost-server"}))
+	require.NoError(t, p.AddSubscription("mattermost/", &Subscription{ChannelID: "1", Repository: "mattermost/"}))
+
+	t.Run("existing subscriptions are replaced", func(t *testing.T) {
+		require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "1", Repository: "mattermost/mattermost-server", Features: "issues"}))
+
+		subs, err := p.GetSubscriptionsByChannel("1")
+		require.NoError(t, err)
+		require.Len(t, subs, 2)
+		assert.Equal(t, "mattermost/", subs[0].Repository)
+		assert.Equal(t, "issues", subs[1].Features)
+	})
+
+	t.Run("repository and organization subscriptions are dispatched", func(t *testing.T) {
+		subs := p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("Mattermost/Mattermost-Server")})
+		assert.Len(t, subs, 3)
+	})
+
+	t.Run("all subscriptions", func(t *testing.T) {
+		all, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Len(t, all.Repositories["mattermost/mattermost-server"], 2)
+		assert.Len(t, all.Repositories["mattermost/"], 1)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		require.NoError(t, p.Unsubscribe("1", "mattermost/mattermost-server", "user"))
+		require.NoError(t, p.Unsubscribe("1", "mattermost", "user"))
+
+		subs, err := p.GetSubscriptionsByChannel("1")
+		require.NoError(t, err)
+		assert.Empty(t, subs)
+
+		all, err := p.GetSubscriptions()
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]*Subscription{
+			"mattermost/mattermost-server": {{ChannelID: "2", Repository: "mattermost/mattermost-server"}},
+		}, all.Repositories)
+	})
+}
+
+func TestMigrateSubscriptions(t *testing.T) {
+	p := pluginWithMockedSubs(nil)
+	api := p.API.(*plugintest.API)
+	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	legacy := Subscriptions{Repositories: map[string][]*Subscription{
+		"mattermost/mattermost-server": {{ChannelID: "1", Repository: "mattermost/mattermost-server"}, {ChannelID: "2", Repository: "mattermost/mattermost-server"}},
+		"mattermost/":                  {{ChannelID: "1", Repository: "mattermost/"}},
+	}}
+	data, err := json.Marshal(legacy)
+	require.NoError(t, err)
+	require.Nil(t, p.API.KVSet(SubscriptionsKey, data))
+
+	require.NoError(t, p.migrateSubscriptions())
+
+	all, err := p.GetSubscriptions()
+	require.NoError(t, err)
+	assert.Equal(t, legacy.Repositories, all.Repositories)
+
+	subs, err := p.GetSubscriptionsByChannel("1")
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	value, appErr := p.API.KVGet(SubscriptionsKey)
+	require.Nil(t, appErr)
+	assert.Nil(t, value)
+
+	// The migration is published once, not once per subscription.
+	api.AssertNumberOfCalls(t, "PublishPluginClusterEvent", 1)
+
+	t.Run("nothing left to migrate", func(t *testing.T) {
+		require.NoError(t, p.migrateSubscriptions())
+		api.AssertNumberOfCalls(t, "PublishPluginClusterEvent", 1)
+	})
+
+	t.Run("excluded repositories", func(t *testing.T) {
+		data, err := json.Marshal([]string{"mattermost/mattermost-webapp", "other/repo"})
+		require.NoError(t, err)
+		require.Nil(t, p.API.KVSet(SubscribedRepoNotificationOff, data))
+
+		require.NoError(t, p.migrateSubscriptions())
+
+		orgSubs, err := p.getRepoSubscriptions("mattermost/")
+		require.NoError(t, err)
+		require.Len(t, orgSubs, 1)
+		assert.Equal(t, []string{"mattermost/mattermost-webapp"}, orgSubs[0].Flags.ExcludeRepos)
+
+		repoSubs, err := p.getRepoSubscriptions("mattermost/mattermost-server")
+		require.NoError(t, err)
+		for _, sub := range repoSubs {
+			assert.Empty(t, sub.Flags.ExcludeRepos)
+		}
+
+		value, appErr := p.API.KVGet(SubscribedRepoNotificationOff)
+		require.Nil(t, appErr)
+		assert.Nil(t, value)
+	})
+}
+
+func TestSubscriptionExcludes(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlagValue(excludeOrgReposFlag, "Mattermost/mattermost-webapp, mattermost/sandbox-*"))
+	assert.Equal(t, []string{"mattermost/mattermost-webapp", "mattermost/sandbox-*"}, flags.ExcludeRepos)
+	assert.Equal(t, "--exclude mattermost/mattermost-webapp,mattermost/sandbox-*", flags.String())
+
+	sub := &Subscription{ChannelID: "1", Repository: "mattermost/", Flags: flags}
+	assert.True(t, sub.Excludes("mattermost/mattermost-webapp"))
+	assert.True(t, sub.Excludes("mattermost/Sandbox-1"))
+	assert.False(t, sub.Excludes("mattermost/mattermost-server"))
+
+	t.Run("only the excluding channel is skipped", func(t *testing.T) {
+		p := pluginWithMockedSubs([]*Subscription{sub, {ChannelID: "2", Repository: "mattermost/"}})
+		p.setConfiguration(&Configuration{})
+
+		subs := p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("mattermost/sandbox-1")})
+		require.Len(t, subs, 1)
+		assert.Equal(t, "2", subs[0].ChannelID)
+
+		assert.Len(t, p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("mattermost/mattermost-server")}), 2)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		assert.Error(t, (&SubscriptionFlags{}).SetFlagValue(excludeOrgReposFlag, "mattermost/[sandbox"))
+	})
+}
+
+func TestSubscriptionTopicAndTeam(t *testing.T) {
+	features, flags, err := parseSubscriptionOptions([]string{"pulls", "--topic", "Team-Platform"})
+	require.NoError(t, err)
+	assert.Equal(t, "pulls", features)
+	assert.Equal(t, "team-platform", flags.Topic)
+	assert.Equal(t, "--topic team-platform", flags.String())
+
+	sub := &Subscription{Repository: "mattermost/", Flags: flags, ResolvedRepos: []string{"mattermost/payments-api"}}
+	assert.False(t, sub.Excludes("Mattermost/Payments-API"))
+	assert.True(t, sub.Excludes("mattermost/mattermost-server"))
+
+	_, _, err = parseSubscriptionOptions([]string{"--team"})
+	assert.EqualError(t, err, "Please specify a value for --team.")
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	err = p.validateSubscription(context.Background(), nil, "mattermost", "mattermost-server", SubscriptionFlags{Team: "platform"})
+	assert.EqualError(t, err, "--topic and --team are only supported on organization level.")
+	err = p.validateSubscription(context.Background(), nil, "mattermost", "", SubscriptionFlags{Team: "platform", Topic: "payments"})
+	assert.EqualError(t, err, "Only one of --topic and --team can be set.")
+}
+
+func TestSubscriptionRepositoryPatterns(t *testing.T) {
+	assert.True(t, isRepositoryPattern("payments-*"))
+	assert.True(t, isRepositoryPattern("service-[ab]"))
+	assert.False(t, isRepositoryPattern("mattermost-server"))
+	assert.False(t, isRepositoryPattern(""))
+
+	p := pluginWithMockedSubs([]*Subscription{
+		{ChannelID: "1", Repository: "mattermost/payments-*"},
+		{ChannelID: "2", Repository: "mattermost/*-infra"},
+		{ChannelID: "3", Repository: "other/payments-*"},
+	})
+	p.setConfiguration(&Configuration{})
+
+	subs := p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("Mattermost/Payments-API")})
+	require.Len(t, subs, 1)
+	assert.Equal(t, "1", subs[0].ChannelID)
+
+	subs = p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("mattermost/payments-infra")})
+	assert.Len(t, subs, 2)
+
+	assert.Empty(t, p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("mattermost/mattermost-server")}))
+
+	t.Run("a pattern is unsubscribed as one subscription", func(t *testing.T) {
+		removed, err := p.removeSubscription("1", "mattermost/payments-*")
+		require.NoError(t, err)
+		require.NotNil(t, removed)
+
+		assert.Empty(t, p.GetSubscribedChannelsForRepository(&github.Repository{FullName: sToP("mattermost/payments-api")}))
+	})
+}
+
+func TestSubscriptionIndex(t *testing.T) {
+	p := pluginWithMockedSubs([]*Subscription{{ChannelID: "1", Repository: "mattermost/mattermost-server"}})
+	p.setConfiguration(&Configuration{})
+	api := p.API.(*plugintest.API)
+
+	repo := &github.Repository{FullName: sToP("mattermost/mattermost-server")}
+	assert.Len(t, p.GetSubscribedChannelsForRepository(repo), 1)
+
+	t.Run("changes on other nodes are picked up after a cluster event", func(t *testing.T) {
+		data, err := json.Marshal([]*Subscription{{ChannelID: "1"}, {ChannelID: "2"}})
+		require.NoError(t, err)
+		require.Nil(t, api.KVSet(getRepoSubscriptionsKey("mattermost/mattermost-server"), data))
+
+		assert.Len(t, p.GetSubscribedChannelsForRepository(repo), 1)
+
+		p.OnPluginClusterEvent(nil, model.PluginClusterEvent{Id: clusterEventSubscriptionsChanged})
+		assert.Len(t, p.GetSubscribedChannelsForRepository(repo), 2)
+	})
+
+	t.Run("local changes are published", func(t *testing.T) {
+		require.NoError(t, p.AddSubscription("mattermost/mattermost-server", &Subscription{ChannelID: "3", Repository: "mattermost/mattermost-server"}))
+
+		assert.Len(t, p.GetSubscribedChannelsForRepository(repo), 3)
+		api.AssertCalled(t, "PublishPluginClusterEvent", model.PluginClusterEvent{Id: clusterEventSubscriptionsChanged}, mock.Anything)
+	})
+}
@@ -181,6 +181,12 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{- end -}}
 `))
 
+	// The eventBody template renders the description of a pull request or issue on its own,
+	// e.g. as the text of a message attachment.
+	template.Must(masterTemplate.New("eventBody").Funcs(funcMap).Parse(
+		`{{. | removeComments | replaceAllGitHubUsernames}}`,
+	))
+
 	template.Must(masterTemplate.New("newPR").Funcs(funcMap).Parse(`
 #### {{.GetPullRequest.GetTitle}}
 ##### {{template "eventRepoPullRequest" .}}
@@ -230,6 +236,26 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 
 	template.Must(masterTemplate.New("reopenedIssue").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} Issue {{template "issue" .GetIssue}} reopened by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("watchedIssueEvent").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Issue {{template "issue" .GetIssue}}
+{{- if eq .GetAction "assigned" }} was assigned to {{template "user" .GetAssignee}}
+{{- else if eq .GetAction "unlabeled" }} had the label ` + "`{{.GetLabel.GetName}}`" + ` removed
+{{- end }} by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("watchedPullRequestEvent").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Pull request {{template "pullRequest" .GetPullRequest}}
+{{- if eq .GetAction "synchronize" }} was updated with new commits
+{{- else if eq .GetAction "reopened" }} was reopened
+{{- else if eq .GetAction "assigned" }} was assigned to {{template "user" .GetAssignee}}
+{{- else if eq .GetAction "unlabeled" }} had the label ` + "`{{.GetLabel.GetName}}`" + ` removed
+{{- else if eq .GetAction "review_requested" }} had a review requested from
+{{- if .RequestedReviewer }} {{template "user" .GetRequestedReviewer}}
+{{- else }} the team ` + "`{{.GetRequestedTeam.GetName}}`" + `
+{{- end }}
+{{- end }} by {{template "user" .GetSender}}.
 `))
 
 	template.Must(masterTemplate.New("pushedCommits").Funcs(funcMap).Parse(`
@@ -251,6 +277,13 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{template "repo" .GetRepo}} New comment by {{template "user" .GetSender}} on {{template "issue" .Issue}}:
 
 {{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+`))
+
+	// The threadComment template renders a comment posted to a Mattermost thread linked to the issue.
+	template.Must(masterTemplate.New("threadComment").Funcs(funcMap).Parse(`
+{{template "user" .GetSender}} [commented]({{.GetComment.GetHTMLURL}}) on {{template "eventRepoIssue" .}}:
+
+{{.GetComment.GetBody | removeComments | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("pullRequestReviewEvent").Funcs(funcMap).Parse(`
@@ -268,6 +301,22 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 
 {{.GetComment.GetDiffHunk}}
 {{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+`))
+
+	template.Must(masterTemplate.New("checkSuiteCompleted").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Checks on [` + "`{{.GetCheckSuite.GetHeadSHA | substr 0 7}}`" + `]({{.GetRepo.GetHTMLURL}}/commit/{{.GetCheckSuite.GetHeadSHA}})
+{{- if eq .GetCheckSuite.GetConclusion "success"}} passed
+{{- else if eq .GetCheckSuite.GetConclusion "failure"}} failed
+{{- else}} completed with conclusion ` + "`{{.GetCheckSuite.GetConclusion}}`" + `
+{{- end}}.
+`))
+
+	template.Must(masterTemplate.New("commitStatus").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Status ` + "`{{.GetContext}}`" + ` on [` + "`{{.GetSHA | substr 0 7}}`" + `]({{.GetRepo.GetHTMLURL}}/commit/{{.GetSHA}})
+{{- if eq .GetState "success"}} passed
+{{- else if eq .GetState "failure"}} failed
+{{- else}} reported ` + "`{{.GetState}}`" + `
+{{- end}}{{if .GetTargetURL}} ([details]({{.GetTargetURL}})){{end}}.
 `))
 
 	template.Must(masterTemplate.New("commentMentionNotification").Funcs(funcMap).Parse(`
@@ -337,7 +386,8 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 		"* `/github help` - Display Slash Command help text\n" +
 		"* `/github todo` - Get a list of unread messages and pull requests awaiting your review\n" +
 		"* `/github subscriptions list` - Will list the current channel subscriptions\n" +
-		"* `/github subscriptions add owner[/repo] [features] [flags]` - Subscribe the current channel to receive notifications about opened pull requests and issues for an organization or repository\n" +
+		"* `/github subscriptions list --all [--repo owner[/repo]] [--team team] [--creator username] [--page number]` - System Admins only: list the subscriptions of all channels with their team, channel, creator, features and flags\n" +
+		"* `/github subscriptions add owner[/repo] [features] [flags]` - Subscribe the current channel to receive notifications about opened pull requests and issues for an organization or repository. Subscribe to all repositories of an organization matching a pattern with `owner/pattern`, like `owner/payments-*`\n" +
 		"  * `features` is a comma-delimited list of one or more the following:\n" +
 		"    * `issues` - includes new and closed issues\n" +
 		"    * `pulls` - includes new and closed pull requests\n" +
@@ -352,11 +402,35 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 		"    * Defaults to `pulls,issues,creates,deletes`\n" +
 		"  * `flags` currently supported:\n" +
 		"    * `--exclude-org-member` - events triggered by organization members will not be delivered (the GitHub organization config should be set, otherwise this flag has not effect)\n" +
+		"    * `--exclude <owner/repo,...>` - organization subscriptions only: events of these repositories will not be posted in this channel. Glob patterns like `owner/sandbox-*` are supported\n" +
+		"    * `--render-style <markdown|attachment>` - render events for this subscription as plain markdown or as message attachments, overriding the plugin default\n" +
+		"    * `--topic <topic>` - organization subscriptions only: only events of repositories with this GitHub topic will be posted\n" +
+		"    * `--team <team>` - organization subscriptions only: only events of repositories of the GitHub team with this slug will be posted\n" +
 		"* `/github subscriptions delete owner[/repo]` - Unsubscribe the current channel from a repository\n" +
+		"* `/github subscriptions export [--all] [--format json|yaml]` - Export the subscriptions of the current channel as a file, sent to you in a direct message. System Admins can export the subscriptions of all channels with `--all`\n" +
+		"* `/github subscriptions import <post link> [--apply]` - Import the subscriptions file attached to a post. The changes are listed and only applied with `--apply`\n" +
+		"* `/github subscriptions history` - Show the recent subscription changes of the current channel, with who made them and when\n" +
 		"* `/github me` - Display the connected GitHub account\n" +
 		"* `/github settings [setting] [value]` - Update your user settings\n" +
 		"  * `setting` can be `notifications` or `reminders`\n" +
 		"  * `value` can be `on` or `off`\n" +
+		"* `/github issue create --repo <owner/repo> [--label <label>] [--assignee <@username|@me>] [--milestone <title>] <title> [-- <body>]` - Create an issue directly. Without flags, `/github issue create [title]` opens the create issue dialog. `--label` and `--assignee` can be repeated\n" +
+		"* `/github issue close <ref> [reason]` and `/github issue reopen <ref> [reason]` - Close or reopen an issue, posting the reason as a comment if provided. `<ref>` is `owner/repo#number`, a URL, or `#number` in a channel with a default repository, see `/github channel set-repo`\n" +
+		"* `/github issue comment <ref> <text>` - Comment on an issue or pull request\n" +
+		"* `/github issue assign <ref> <@username...>` - Assign Mattermost users, mapped to their connected GitHub accounts, or GitHub usernames given without @\n" +
+		"* `/github issue label <ref> <+label|-label...>` - Add labels prefixed with `+` and remove labels prefixed with `-`\n" +
+		"* `/github issue link <owner/repo#number|url>` - Link the current thread to an issue or pull request. Replies in the thread are posted as comments and new comments are posted to the thread\n" +
+		"* `/github issue unlink` - Remove the link of the current thread\n" +
+		"* `/github watch <owner/repo#number|url>` - Post comments, reviews, review requests, assignments, labels, new commits, check results and the merge or close of an issue or pull request in the current channel, or thread if run in a reply, until it is closed\n" +
+		"* `/github watch list` - List the issues and pull requests watched in the current channel\n" +
+		"* `/github watch stop <owner/repo#number|url>` - Stop watching an issue or pull request in the current channel or thread\n" +
+		"* `/github pr list [owner/repo] [--mine|--review-requested]` - List open pull requests of a repository, or the ones you opened or are requested to review\n" +
+		"* `/github pr view <owner/repo#number|url>` - Show the state, reviewers and description of a pull request\n" +
+		"* `/github pr approve <owner/repo#number|url> [comment]` - Approve a pull request, optionally with a comment\n" +
+		"* `/github pr merge <owner/repo#number|url> [--squash|--rebase]` - Merge a pull request\n" +
+		"* `/github pr request-review <owner/repo#number|url> <@username...>` - Request reviews from Mattermost users, mapped to their connected GitHub accounts, or from GitHub usernames given without @\n" +
+		"* `/github channel set-repo <owner/repo>` - Set the default repository of the channel. `/github issue create`, `/github pr list`, `/github subscriptions add` and `#number` references fall back to it, and the create issue dialog preselects it. Without a repository, shows the current default\n" +
+		"* `/github channel unset-repo` - Remove the default repository of the channel\n" +
 		"* `/github mute` - Managed muted GitHub users. You will not receive notifications for comments in your PRs and issues from those users.\n" +
 		"  * `/github mute list` - list your muted GitHub users\n" +
 		"  * `/github mute add [username]` - add a GitHub user to your muted list\n" +
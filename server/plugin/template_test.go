@@ -453,6 +453,22 @@ func TestClosedIssueTemplate(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestCheckSuiteCompletedTemplate(t *testing.T) {
+	expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Checks on [` + "`a10867b`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a10867b14bb761a232cd80139fbd4c0d33264240) failed.
+`
+
+	actual, err := renderTemplate("checkSuiteCompleted", &github.CheckSuiteEvent{
+		Repo: &repo,
+		CheckSuite: &github.CheckSuite{
+			HeadSHA:    sToP("a10867b14bb761a232cd80139fbd4c0d33264240"),
+			Conclusion: sToP("failure"),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func TestReopenedIssueTemplate(t *testing.T) {
 	expected := `
 [\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Issue [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1) reopened by [panda](https://github.com/panda).
@@ -467,6 +483,96 @@ func TestReopenedIssueTemplate(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestWatchedIssueEventTemplate(t *testing.T) {
+	t.Run("assigned", func(t *testing.T) {
+		expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Issue [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1) was assigned to [panda](https://github.com/panda) by [panda](https://github.com/panda).
+`
+
+		actual, err := renderTemplate("watchedIssueEvent", &github.IssuesEvent{
+			Action:   sToP(actionAssigned),
+			Repo:     &repo,
+			Issue:    &issue,
+			Assignee: &user,
+			Sender:   &user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("unlabeled", func(t *testing.T) {
+		expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Issue [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1) had the label ` + "`bug`" + ` removed by [panda](https://github.com/panda).
+`
+
+		actual, err := renderTemplate("watchedIssueEvent", &github.IssuesEvent{
+			Action: sToP(actionUnlabeled),
+			Repo:   &repo,
+			Issue:  &issue,
+			Label:  &github.Label{Name: sToP("bug")},
+			Sender: &user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+}
+
+func TestWatchedPullRequestEventTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		event    *github.PullRequestEvent
+		expected string
+	}{
+		{
+			name:     "synchronize",
+			event:    &github.PullRequestEvent{Action: sToP(actionSynchronize)},
+			expected: "was updated with new commits",
+		}, {
+			name:     "reopened",
+			event:    &github.PullRequestEvent{Action: sToP(actionReopened)},
+			expected: "was reopened",
+		}, {
+			name:     "review requested from a user",
+			event:    &github.PullRequestEvent{Action: sToP(actionReviewRequested), RequestedReviewer: &user},
+			expected: "had a review requested from [panda](https://github.com/panda)",
+		}, {
+			name:     "review requested from a team",
+			event:    &github.PullRequestEvent{Action: sToP(actionReviewRequested), RequestedTeam: &github.Team{Name: sToP("Platform")}},
+			expected: "had a review requested from the team `Platform`",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.event.Repo = &repo
+			tc.event.PullRequest = &pullRequest
+			tc.event.Sender = &user
+
+			expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Pull request [#42 Leverage git-get-head](https://github.com/mattermost/mattermost-plugin-github/pull/42) ` + tc.expected + ` by [panda](https://github.com/panda).
+`
+
+			actual, err := renderTemplate("watchedPullRequestEvent", tc.event)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestCommitStatusTemplate(t *testing.T) {
+	expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) Status ` + "`ci/jenkins`" + ` on [` + "`a10867b`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a10867b14bb761a232cd80139fbd4c0d33264240) failed ([details](https://ci.example.com/1)).
+`
+
+	actual, err := renderTemplate("commitStatus", &github.StatusEvent{
+		Repo:      &repo,
+		SHA:       sToP("a10867b14bb761a232cd80139fbd4c0d33264240"),
+		State:     sToP("failure"),
+		Context:   sToP("ci/jenkins"),
+		TargetURL: sToP("https://ci.example.com/1"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func TestIssueLabelledTemplate(t *testing.T) {
 	expected := `
 #### Implement git-get-head
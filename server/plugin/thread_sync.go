@@ -0,0 +1,355 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const (
+	threadLinkKey   = "_threadlink"
+	issueThreadsKey = "issuethreads_"
+	// linkedThreadsKey stores the root IDs of all linked threads.
+	linkedThreadsKey = "linkedthreads"
+
+	clusterEventThreadLinksChanged = "thread_links_changed"
+
+	// syncedCommentMarker is hidden in the body of comments created from Mattermost,
+	// so that their webhook events aren't posted back to the threads they came from.
+	syncedCommentMarker = "<!-- mattermost-thread-sync -->"
+)
+
+// threadLinkIndex keeps the root IDs of all linked threads in memory, so that replies in threads
+// that aren't linked don't need to read the KV store. It is loaded lazily and dropped whenever
+// a thread is linked or unlinked on any node of the cluster.
+type threadLinkIndex struct {
+	lock sync.RWMutex
+
+	// rootIDs is nil until loaded.
+	rootIDs map[string]bool
+}
+
+// ThreadLink links a Mattermost thread to a GitHub issue or pull request.
+type ThreadLink struct {
+	RootID    string `json:"root_id"`
+	ChannelID string `json:"channel_id"`
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Number    int    `json:"number"`
+	CreatorID string `json:"creator_id"`
+}
+
+// IssueURL returns the link to the linked issue or pull request. GitHub redirects
+// issue links of pull requests to the pull request itself.
+func (l *ThreadLink) IssueURL(baseURL string) string {
+	return fmt.Sprintf("%s%s/issues/%d", baseURL, fullNameFromOwnerAndRepo(l.Owner, l.Repo), l.Number)
+}
+
+// IssueName returns the short reference of the linked issue or pull request, e.g. owner/repo#1.
+func (l *ThreadLink) IssueName() string {
+	return fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(l.Owner, l.Repo), l.Number)
+}
+
+// getIssueThreadsKey returns the key of the list of threads linked to an issue. GitHub names
+// are case-insensitive, and the reference is hashed to stay within the KV key length limit.
+func getIssueThreadsKey(owner, repo string, number int) string {
+	ref := strings.ToLower(fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number))
+	return fmt.Sprintf("%s%x", issueThreadsKey, sha256.Sum256([]byte(ref)))
+}
+
+func (p *Plugin) getThreadLink(rootID string) (*ThreadLink, error) {
+	data, appErr := p.API.KVGet(rootID + threadLinkKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get thread link from KVStore")
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var link *ThreadLink
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal thread link")
+	}
+
+	return link, nil
+}
+
+func (p *Plugin) getIssueThreadIDs(owner, repo string, number int) ([]string, error) {
+	data, appErr := p.API.KVGet(getIssueThreadsKey(owner, repo, number))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get linked threads from KVStore")
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var rootIDs []string
+	if err := json.Unmarshal(data, &rootIDs); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal linked threads")
+	}
+
+	return rootIDs, nil
+}
+
+// LinkThread links the thread of link.RootID to an issue or pull request,
+// replacing any previous link of the thread.
+func (p *Plugin) LinkThread(link *ThreadLink) error {
+	if err := p.UnlinkThread(link.RootID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal thread link")
+	}
+
+	if appErr := p.API.KVSet(link.RootID+threadLinkKey, data); appErr != nil {
+		return errors.Wrap(appErr, "could not store thread link in KVStore")
+	}
+
+	if err := p.modifyStringList(getIssueThreadsKey(link.Owner, link.Repo, link.Number), link.RootID, true); err != nil {
+		return errors.Wrap(err, "could not store linked threads")
+	}
+
+	return p.updateLinkedThreads(link.RootID, true)
+}
+
+// UnlinkThread removes the link of the thread of rootID, if any.
+func (p *Plugin) UnlinkThread(rootID string) error {
+	link, err := p.getThreadLink(rootID)
+	if err != nil || link == nil {
+		return err
+	}
+
+	if appErr := p.API.KVDelete(rootID + threadLinkKey); appErr != nil {
+		return errors.Wrap(appErr, "could not delete thread link from KVStore")
+	}
+
+	if err := p.modifyStringList(getIssueThreadsKey(link.Owner, link.Repo, link.Number), rootID, false); err != nil {
+		return errors.Wrap(err, "could not update linked threads")
+	}
+
+	return p.updateLinkedThreads(rootID, false)
+}
+
+// updateLinkedThreads adds or removes rootID from the list of all linked threads,
+// and drops the in-memory index of all nodes.
+func (p *Plugin) updateLinkedThreads(rootID string, linked bool) error {
+	if err := p.modifyStringList(linkedThreadsKey, rootID, linked); err != nil {
+		return errors.Wrap(err, "could not update linked threads")
+	}
+
+	p.resetThreadLinkIndex()
+
+	err := p.API.PublishPluginClusterEvent(
+		model.PluginClusterEvent{Id: clusterEventThreadLinksChanged},
+		model.PluginClusterEventSendOptions{SendType: model.PluginClusterEventSendTypeReliable},
+	)
+	if err != nil {
+		p.API.LogWarn("Failed to publish thread link change to the cluster", "error", err.Error())
+	}
+
+	return nil
+}
+
+// isThreadLinked reports whether the thread of rootID is linked, loading the index of linked threads if needed.
+func (p *Plugin) isThreadLinked(rootID string) (bool, error) {
+	index := &p.threadLinkIndex
+
+	index.lock.RLock()
+	rootIDs := index.rootIDs
+	index.lock.RUnlock()
+	if rootIDs != nil {
+		return rootIDs[rootID], nil
+	}
+
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	if index.rootIDs == nil {
+		data, appErr := p.API.KVGet(linkedThreadsKey)
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "could not get linked threads from KVStore")
+		}
+
+		ids, err := decodeStringList(data)
+		if err != nil {
+			return false, errors.Wrap(err, "could not decode linked threads")
+		}
+
+		index.rootIDs = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			index.rootIDs[id] = true
+		}
+	}
+
+	return index.rootIDs[rootID], nil
+}
+
+// resetThreadLinkIndex drops the in-memory index of linked threads of this node.
+func (p *Plugin) resetThreadLinkIndex() {
+	p.threadLinkIndex.lock.Lock()
+	defer p.threadLinkIndex.lock.Unlock()
+
+	p.threadLinkIndex.rootIDs = nil
+}
+
+// getLinkedThreads returns the threads linked to an issue or pull request.
+func (p *Plugin) getLinkedThreads(owner, repo string, number int) []*ThreadLink {
+	rootIDs, err := p.getIssueThreadIDs(owner, repo, number)
+	if err != nil {
+		p.API.LogWarn("Failed to get linked threads", "error", err.Error())
+		return nil
+	}
+
+	var links []*ThreadLink
+	for _, rootID := range rootIDs {
+		link, err := p.getThreadLink(rootID)
+		if err != nil {
+			p.API.LogWarn("Failed to get thread link", "root_id", rootID, "error", err.Error())
+			continue
+		}
+		if link != nil {
+			links = append(links, link)
+		}
+	}
+
+	return links
+}
+
+// isSyncedComment reports whether a GitHub comment was created from Mattermost.
+func isSyncedComment(comment *github.IssueComment) bool {
+	return strings.Contains(comment.GetBody(), syncedCommentMarker)
+}
+
+// createLinkAnnouncements shows the new link in the thread and on the issue or pull request.
+func (p *Plugin) createLinkAnnouncements(ctx context.Context, githubClient *github.Client, link *ThreadLink) error {
+	reply := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: link.ChannelID,
+		RootId:    link.RootID,
+		Message: fmt.Sprintf("This thread is linked to [%s](%s). Replies in this thread are posted as comments by their authors, and new comments are posted here.",
+			link.IssueName(), link.IssueURL(p.getBaseURL())),
+	}
+	if _, appErr := p.API.CreatePost(reply); appErr != nil {
+		return errors.Wrap(appErr, "failed to create link announcement post")
+	}
+
+	body := fmt.Sprintf("This conversation is linked to a [Mattermost thread](%s). Comments are synced in both directions.\n%s", p.getPermaLink(link.RootID), syncedCommentMarker)
+	if _, _, err := githubClient.Issues.CreateComment(ctx, link.Owner, link.Repo, link.Number, &github.IssueComment{Body: &body}); err != nil {
+		return errors.Wrap(err, "failed to create link announcement comment")
+	}
+
+	return nil
+}
+
+// MessageHasBeenPosted posts replies in linked threads as comments on the linked issue or pull request.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	if post.RootId == "" || post.UserId == "" {
+		return
+	}
+
+	linked, err := p.isThreadLinked(post.RootId)
+	if err != nil {
+		p.API.LogWarn("Failed to check if the thread is linked", "root_id", post.RootId, "error", err.Error())
+		return
+	}
+	if !linked {
+		return
+	}
+
+	link, err := p.getThreadLink(post.RootId)
+	if err != nil {
+		p.API.LogWarn("Failed to get thread link", "root_id", post.RootId, "error", err.Error())
+		return
+	}
+	if link == nil {
+		return
+	}
+
+	client := pluginapi.NewClient(p.API, p.Driver)
+	shouldProcessMessage, err := client.Post.ShouldProcessMessage(post, pluginapi.BotID(p.BotUserID))
+	if err != nil {
+		p.API.LogWarn("Error while checking if the message should be processed", "error", err.Error())
+		return
+	}
+	if !shouldProcessMessage {
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(post.UserId)
+	if apiErr != nil {
+		p.API.SendEphemeralPost(post.UserId, &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: post.ChannelId,
+			RootId:    post.RootId,
+			Message:   fmt.Sprintf("Your reply was not posted to [%s](%s) because your account is not connected to GitHub. Enter `/github connect` to connect it.", link.IssueName(), link.IssueURL(p.getBaseURL())),
+		})
+		return
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, info)
+
+	body := fmt.Sprintf("%s\n\n<sub>Posted from [Mattermost](%s)</sub>\n%s", post.Message, p.getPermaLink(post.Id), syncedCommentMarker)
+	_, resp, err := githubClient.Issues.CreateComment(ctx, link.Owner, link.Repo, link.Number, &github.IssueComment{Body: &body})
+	if err != nil {
+		p.API.LogWarn("Failed to sync reply to GitHub", "issue", link.IssueName(), "error", err.Error())
+		p.API.SendEphemeralPost(post.UserId, &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: post.ChannelId,
+			RootId:    post.RootId,
+			Message:   fmt.Sprintf("Failed to post your reply to [%s](%s): %s", link.IssueName(), link.IssueURL(p.getBaseURL()), getActionFailReason(resp, err, "comment", fullNameFromOwnerAndRepo(link.Owner, link.Repo), info.GitHubUsername)),
+		})
+		return
+	}
+}
+
+// postIssueCommentToThreads posts new comments on an issue or pull request to its linked threads.
+// Comments that were created from Mattermost are skipped.
+func (p *Plugin) postIssueCommentToThreads(event *github.IssueCommentEvent) {
+	if event.GetAction() != actionCreated {
+		return
+	}
+
+	comment := event.GetComment()
+	if isSyncedComment(comment) {
+		return
+	}
+
+	repo := event.GetRepo()
+	links := p.getLinkedThreads(repo.GetOwner().GetLogin(), repo.GetName(), event.GetIssue().GetNumber())
+	if len(links) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("threadComment", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	for _, link := range links {
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: link.ChannelID,
+			RootId:    link.RootID,
+			Message:   message,
+		}
+		setGitHubObjectProps(post, githubObjectIssueComment, repo.GetFullName(), event.GetIssue().GetNumber(), comment.GetID())
+
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Error posting comment to linked thread", "root_id", link.RootID, "error", appErr.Error())
+		}
+	}
+}
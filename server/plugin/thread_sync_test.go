@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkThread(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("PublishPluginClusterEvent", mock.Anything, mock.Anything).Return(nil)
+	p.SetAPI(api)
+
+	first := &ThreadLink{RootID: "root1", ChannelID: "channel", Owner: "mattermost", Repo: "mattermost-server", Number: 1}
+	second := &ThreadLink{RootID: "root2", ChannelID: "channel", Owner: "mattermost", Repo: "mattermost-server", Number: 1}
+
+	require.NoError(t, p.LinkThread(first))
+	require.NoError(t, p.LinkThread(second))
+
+	links := p.getLinkedThreads("mattermost", "mattermost-server", 1)
+	assert.Equal(t, []*ThreadLink{first, second}, links)
+	assert.Equal(t, links, p.getLinkedThreads("Mattermost", "Mattermost-Server", 1), "GitHub names are case-insensitive")
+
+	linked, err := p.isThreadLinked("root1")
+	require.NoError(t, err)
+	assert.True(t, linked)
+	linked, err = p.isThreadLinked("other")
+	require.NoError(t, err)
+	assert.False(t, linked)
+
+	t.Run("relinking moves the thread", func(t *testing.T) {
+		moved := &ThreadLink{RootID: "root1", ChannelID: "channel", Owner: "mattermost", Repo: "mattermost-server", Number: 2}
+		require.NoError(t, p.LinkThread(moved))
+
+		assert.Equal(t, []*ThreadLink{second}, p.getLinkedThreads("mattermost", "mattermost-server", 1))
+		assert.Equal(t, []*ThreadLink{moved}, p.getLinkedThreads("mattermost", "mattermost-server", 2))
+	})
+
+	t.Run("unlink", func(t *testing.T) {
+		require.NoError(t, p.UnlinkThread("root1"))
+		require.NoError(t, p.UnlinkThread("root2"))
+		require.NoError(t, p.UnlinkThread("unknown"))
+
+		assert.Empty(t, p.getLinkedThreads("mattermost", "mattermost-server", 1))
+		assert.Empty(t, store)
+
+		linked, err := p.isThreadLinked("root2")
+		require.NoError(t, err)
+		assert.False(t, linked)
+	})
+}
+
+func TestIsSyncedComment(t *testing.T) {
+	assert.True(t, isSyncedComment(&github.IssueComment{Body: sToP("Looks good\n\n<sub>Posted from [Mattermost](http://localhost/_redirect/pl/post)</sub>\n" + syncedCommentMarker)}))
+	assert.False(t, isSyncedComment(&github.IssueComment{Body: sToP("Posted from Mattermost")}))
+	assert.False(t, isSyncedComment(&github.IssueComment{}))
+}
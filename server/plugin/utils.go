@@ -131,6 +131,38 @@ func parseOwnerAndRepo(full, baseURL string) (string, string) {
 	return owner, repo
 }
 
+// parseIssueRef parses a reference to an issue or pull request. Both the short form
+// owner/repo#number and links like baseURL/owner/repo/issues/number are accepted.
+func parseIssueRef(ref, baseURL string) (owner, repo string, number int, err error) {
+	ref = strings.TrimSpace(ref)
+	ref = strings.TrimPrefix(ref, baseURL)
+	ref = strings.TrimPrefix(strings.TrimPrefix(ref, "https://"), "http://")
+	ref = strings.TrimPrefix(ref, "github.com/")
+
+	var numberPart string
+	if strings.Contains(ref, "/issues/") || strings.Contains(ref, "/pull/") {
+		if i := strings.IndexAny(ref, "#?"); i >= 0 {
+			ref = ref[:i]
+		}
+		parts := strings.Split(strings.Trim(ref, "/"), "/")
+		if len(parts) < 4 || (parts[2] != "issues" && parts[2] != "pull") {
+			return "", "", 0, errors.Errorf("invalid issue or pull request reference %q", ref)
+		}
+		owner, repo = parts[0], parts[1]
+		numberPart = parts[3]
+	} else if i := strings.LastIndex(ref, "#"); i >= 0 {
+		owner, repo = parseOwnerAndRepo(ref[:i], "")
+		numberPart = ref[i+1:]
+	}
+
+	number, err = strconv.Atoi(numberPart)
+	if err != nil || number <= 0 || owner == "" || repo == "" {
+		return "", "", 0, errors.Errorf("invalid issue or pull request reference %q", ref)
+	}
+
+	return owner, repo, number, nil
+}
+
 func parseGitHubUsernamesFromText(text string) []string {
 	usernameMap := map[string]bool{}
 	usernames := []string{}
@@ -178,6 +210,14 @@ func isFlag(text string) bool {
 	return strings.HasPrefix(text, "--")
 }
 
+// trimQuotes removes the double quotes around a command parameter, e.g. "Fix the build".
+func trimQuotes(text string) string {
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
 func parseFlag(flag string) string {
 	return strings.TrimPrefix(flag, "--")
 }
@@ -294,6 +334,17 @@ func isInsideLink(msg string, index int) bool {
 	return false
 }
 
+// markdownEscaper escapes the characters that have a meaning in markdown text.
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "~", "\\~", "#", "\\#",
+	"[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "<", "\\<", ">", "\\>", "|", "\\|",
+)
+
+// escapeMarkdown escapes text so that it is rendered as is, e.g. inside the text of a link.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
 // getCodeMarkdown returns the constructed markdown for a permalink.
 func getCodeMarkdown(user, repo, repoPath, word, lines string, isTruncated bool) string {
 	final := fmt.Sprintf("\n[%s/%s/%s](%s)\n", user, repo, repoPath, word)
@@ -84,6 +84,42 @@ func TestParseOwnerAndRepo(t *testing.T) {
 	}
 }
 
+func TestParseIssueRef(t *testing.T) {
+	tcs := []struct {
+		Ref            string
+		BaseURL        string
+		ExpectedOwner  string
+		ExpectedRepo   string
+		ExpectedNumber int
+		ExpectedError  bool
+	}{
+		{Ref: "mattermost/mattermost-server#42", BaseURL: "https://github.com/", ExpectedOwner: "mattermost", ExpectedRepo: "mattermost-server", ExpectedNumber: 42},
+		{Ref: "https://github.com/mattermost/mattermost-server/issues/42", BaseURL: "https://github.com/", ExpectedOwner: "mattermost", ExpectedRepo: "mattermost-server", ExpectedNumber: 42},
+		{Ref: "https://github.com/mattermost/mattermost-server/pull/42/files", BaseURL: "https://github.com/", ExpectedOwner: "mattermost", ExpectedRepo: "mattermost-server", ExpectedNumber: 42},
+		{Ref: "https://github.com/mattermost/mattermost-server/issues/42#issuecomment-1", BaseURL: "https://github.com/", ExpectedOwner: "mattermost", ExpectedRepo: "mattermost-server", ExpectedNumber: 42},
+		{Ref: "https://example.org/mattermost/mattermost-server/pull/42", BaseURL: "https://example.org/", ExpectedOwner: "mattermost", ExpectedRepo: "mattermost-server", ExpectedNumber: 42},
+		{Ref: "mattermost/mattermost-server", BaseURL: "https://github.com/", ExpectedError: true},
+		{Ref: "mattermost#42", BaseURL: "https://github.com/", ExpectedError: true},
+		{Ref: "mattermost/mattermost-server#abc", BaseURL: "https://github.com/", ExpectedError: true},
+		{Ref: "", BaseURL: "https://github.com/", ExpectedError: true},
+	}
+
+	for i, tc := range tcs {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			owner, repo, number, err := parseIssueRef(tc.Ref, tc.BaseURL)
+			if tc.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ExpectedOwner, owner)
+			assert.Equal(t, tc.ExpectedRepo, repo)
+			assert.Equal(t, tc.ExpectedNumber, number)
+		})
+	}
+}
+
 func TestIsFlag(t *testing.T) {
 	tcs := []struct {
 		Text     string
@@ -216,6 +252,10 @@ func TestInsideLink(t *testing.T) {
 	}
 }
 
+func TestEscapeMarkdown(t *testing.T) {
+	assert.Equal(t, "Fix \\*bold\\* \\_and\\_ \\[links\\]\\(url\\) in \\`C:\\\\path\\`", escapeMarkdown("Fix *bold* _and_ [links](url) in `C:\\path`"))
+}
+
 func TestGetToDoDisplayText(t *testing.T) {
 	type input struct {
 		title     string
@@ -0,0 +1,597 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/pkg/errors"
+
+	pluginapi "github.com/mattermost/mattermost-plugin-api"
+)
+
+const (
+	issueWatchesKey   = "issuewatches_"
+	channelWatchesKey = "_channelwatches"
+	watchedHeadKey    = "watchedhead_"
+
+	// watchedHeadTTL is how long the head commit of a watched pull request is remembered for matching CI events.
+	watchedHeadTTL = 30 * 24 * 60 * 60
+)
+
+// Watch posts all events of a single issue or pull request to a channel, or to a thread if RootID is set.
+// Watches end when the issue or pull request is closed.
+type Watch struct {
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id,omitempty"`
+	CreatorID string `json:"creator_id"`
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	Number    int    `json:"number"`
+}
+
+// IssueURL returns the link to the watched issue or pull request.
+func (w *Watch) IssueURL(baseURL string) string {
+	return fmt.Sprintf("%s%s/issues/%d", baseURL, fullNameFromOwnerAndRepo(w.Owner, w.Repo), w.Number)
+}
+
+// IssueName returns the short reference of the watched issue or pull request, e.g. owner/repo#1.
+func (w *Watch) IssueName() string {
+	return fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(w.Owner, w.Repo), w.Number)
+}
+
+// getIssueWatchesKey returns the key of the watches of an issue.
+// The reference is hashed to stay within the KV key length limit.
+func getIssueWatchesKey(owner, repo string, number int) string {
+	ref := strings.ToLower(fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number))
+	return fmt.Sprintf("%s%x", issueWatchesKey, sha256.Sum256([]byte(ref)))
+}
+
+func decodeWatches(data []byte) ([]*Watch, error) {
+	var watches []*Watch
+	if len(data) == 0 {
+		return watches, nil
+	}
+
+	if err := json.Unmarshal(data, &watches); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal watches")
+	}
+
+	return watches, nil
+}
+
+func (p *Plugin) getIssueWatches(owner, repo string, number int) ([]*Watch, error) {
+	data, appErr := p.API.KVGet(getIssueWatchesKey(owner, repo, number))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get watches from KVStore")
+	}
+
+	return decodeWatches(data)
+}
+
+// AddWatch stores watch, replacing a previous watch of the same issue by the same channel or thread.
+func (p *Plugin) AddWatch(watch *Watch) error {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	err := client.KV.SetAtomicWithRetries(getIssueWatchesKey(watch.Owner, watch.Repo, watch.Number), func(oldValue []byte) (interface{}, error) {
+		watches, err := decodeWatches(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		for index, w := range watches {
+			if w.ChannelID == watch.ChannelID && w.RootID == watch.RootID {
+				watches[index] = watch
+				return watches, nil
+			}
+		}
+
+		return append(watches, watch), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not store watch")
+	}
+
+	if err := p.modifyStringList(watch.ChannelID+channelWatchesKey, strings.ToLower(watch.IssueName()), true); err != nil {
+		return errors.Wrap(err, "could not store channel watches")
+	}
+
+	return nil
+}
+
+// RemoveWatch removes the watch of an issue by a channel or thread. It reports whether there was one.
+func (p *Plugin) RemoveWatch(channelID, rootID, owner, repo string, number int) (bool, error) {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	removed := false
+	channelWatches := 0
+	err := client.KV.SetAtomicWithRetries(getIssueWatchesKey(owner, repo, number), func(oldValue []byte) (interface{}, error) {
+		watches, err := decodeWatches(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		removed = false
+		channelWatches = 0
+		kept := make([]*Watch, 0, len(watches))
+		for _, w := range watches {
+			if w.ChannelID == channelID && w.RootID == rootID {
+				removed = true
+				continue
+			}
+			if w.ChannelID == channelID {
+				channelWatches++
+			}
+			kept = append(kept, w)
+		}
+
+		if len(kept) == 0 {
+			return nil, nil
+		}
+
+		return kept, nil
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "could not remove watch")
+	}
+
+	if removed && channelWatches == 0 {
+		ref := strings.ToLower(fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number))
+		if err := p.modifyStringList(channelID+channelWatchesKey, ref, false); err != nil {
+			return true, errors.Wrap(err, "could not remove channel watch")
+		}
+	}
+
+	return removed, nil
+}
+
+// endWatches removes all watches of an issue and returns them.
+func (p *Plugin) endWatches(owner, repo string, number int) ([]*Watch, error) {
+	client := pluginapi.NewClient(p.API, p.Driver)
+
+	var watches []*Watch
+	err := client.KV.SetAtomicWithRetries(getIssueWatchesKey(owner, repo, number), func(oldValue []byte) (interface{}, error) {
+		var err error
+		watches, err = decodeWatches(oldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not delete watches")
+	}
+
+	ref := strings.ToLower(fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number))
+	for _, w := range watches {
+		if err := p.modifyStringList(w.ChannelID+channelWatchesKey, ref, false); err != nil {
+			p.API.LogWarn("Failed to remove channel watch", "channel_id", w.ChannelID, "issue", ref, "error", err.Error())
+		}
+	}
+
+	return watches, nil
+}
+
+// getWatchedHeadKey returns the key of the watched pull request whose head is the commit sha of repo.
+func getWatchedHeadKey(owner, repo, sha string) string {
+	ref := strings.ToLower(fmt.Sprintf("%s@%s", fullNameFromOwnerAndRepo(owner, repo), sha))
+	return fmt.Sprintf("%s%x", watchedHeadKey, sha256.Sum256([]byte(ref)))
+}
+
+// storeWatchedHead remembers that sha is the head commit of the watched pull request number of repo.
+// Check suites of pull requests from forks don't list their pull requests, and commit statuses never do,
+// so their events are matched to watches by commit.
+func (p *Plugin) storeWatchedHead(owner, repo, sha string, number int) {
+	if sha == "" {
+		return
+	}
+
+	if appErr := p.API.KVSetWithExpiry(getWatchedHeadKey(owner, repo, sha), []byte(strconv.Itoa(number)), watchedHeadTTL); appErr != nil {
+		p.API.LogWarn("Failed to store head of watched pull request", "repo", fullNameFromOwnerAndRepo(owner, repo), "number", number, "error", appErr.Error())
+	}
+}
+
+// getWatchedHead returns the number of the watched pull request whose head is the commit sha of repo, or 0 if there is none.
+func (p *Plugin) getWatchedHead(owner, repo, sha string) int {
+	data, appErr := p.API.KVGet(getWatchedHeadKey(owner, repo, sha))
+	if appErr != nil {
+		p.API.LogWarn("Failed to get watched pull request of commit", "repo", fullNameFromOwnerAndRepo(owner, repo), "sha", sha, "error", appErr.Error())
+		return 0
+	}
+
+	number, _ := strconv.Atoi(string(data))
+	return number
+}
+
+// getChannelWatches returns the watches of a channel and its threads.
+func (p *Plugin) getChannelWatches(channelID string) ([]*Watch, error) {
+	data, appErr := p.API.KVGet(channelID + channelWatchesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get channel watches from KVStore")
+	}
+
+	refs, err := decodeStringList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var channelWatches []*Watch
+	for _, ref := range refs {
+		owner, repo, number, err := parseIssueRef(ref, "")
+		if err != nil {
+			continue
+		}
+
+		watches, err := p.getIssueWatches(owner, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range watches {
+			if w.ChannelID == channelID {
+				channelWatches = append(channelWatches, w)
+			}
+		}
+	}
+
+	return channelWatches, nil
+}
+
+// postToWatches posts message to the watches of an issue or pull request of repo.
+// Events of private repositories are only posted for watches whose creator can still access them.
+func (p *Plugin) postToWatches(repo *github.Repository, number int, message string) {
+	watches, err := p.getIssueWatches(repo.GetOwner().GetLogin(), repo.GetName(), number)
+	if err != nil {
+		p.API.LogWarn("Failed to get watches", "repo", repo.GetFullName(), "number", number, "error", err.Error())
+		return
+	}
+
+	p.createWatchPosts(repo, watches, message)
+}
+
+func (p *Plugin) createWatchPosts(repo *github.Repository, watches []*Watch, message string) {
+	for _, w := range watches {
+		if repo.GetPrivate() && !p.permissionToRepo(w.CreatorID, repo.GetFullName()) {
+			continue
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: w.ChannelID,
+			RootId:    w.RootID,
+			Message:   message,
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Error posting to watch", "channel_id", w.ChannelID, "issue", w.IssueName(), "error", appErr.Error())
+		}
+	}
+}
+
+// postClosedToWatches posts message to the watches of a closed issue or pull request and ends them.
+func (p *Plugin) postClosedToWatches(repo *github.Repository, number int, message string) {
+	watches, err := p.endWatches(repo.GetOwner().GetLogin(), repo.GetName(), number)
+	if err != nil {
+		p.API.LogWarn("Failed to end watches", "repo", repo.GetFullName(), "number", number, "error", err.Error())
+		return
+	}
+
+	p.createWatchPosts(repo, watches, strings.TrimSpace(message)+" This watch has ended.")
+}
+
+func (p *Plugin) postIssueEventToWatches(event *github.IssuesEvent) {
+	templateName := ""
+	switch event.GetAction() {
+	case actionLabeled:
+		templateName = "issueLabelled"
+	case actionClosed:
+		templateName = "closedIssue"
+	case actionReopened:
+		templateName = "reopenedIssue"
+	case actionAssigned, actionUnlabeled:
+		templateName = "watchedIssueEvent"
+	default:
+		return
+	}
+
+	message, err := renderTemplate(templateName, event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	if event.GetAction() == actionClosed {
+		p.postClosedToWatches(event.GetRepo(), event.GetIssue().GetNumber(), message)
+		return
+	}
+	p.postToWatches(event.GetRepo(), event.GetIssue().GetNumber(), message)
+}
+
+func (p *Plugin) postPullRequestEventToWatches(event *github.PullRequestEvent) {
+	templateName := ""
+	switch event.GetAction() {
+	case actionLabeled:
+		templateName = "pullRequestLabelled"
+	case actionClosed:
+		templateName = "closedPR"
+	case actionSynchronize, actionReopened, actionAssigned, actionUnlabeled, actionReviewRequested:
+		templateName = "watchedPullRequestEvent"
+	default:
+		return
+	}
+
+	repo := event.GetRepo()
+	pr := event.GetPullRequest()
+	watches, err := p.getIssueWatches(repo.GetOwner().GetLogin(), repo.GetName(), pr.GetNumber())
+	if err != nil {
+		p.API.LogWarn("Failed to get watches", "repo", repo.GetFullName(), "number", pr.GetNumber(), "error", err.Error())
+		return
+	}
+	if len(watches) == 0 {
+		return
+	}
+
+	message, err := renderTemplate(templateName, event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	if event.GetAction() == actionClosed {
+		p.postClosedToWatches(repo, pr.GetNumber(), message)
+		return
+	}
+
+	if event.GetAction() == actionSynchronize || event.GetAction() == actionReopened {
+		p.storeWatchedHead(repo.GetOwner().GetLogin(), repo.GetName(), pr.GetHead().GetSHA(), pr.GetNumber())
+	}
+	p.createWatchPosts(repo, watches, message)
+}
+
+// postIssueCommentToWatches posts new comments to the watches of an issue or pull request.
+// Threads linked to the issue already get its comments, so their watches are skipped.
+func (p *Plugin) postIssueCommentToWatches(event *github.IssueCommentEvent) {
+	if event.GetAction() != actionCreated {
+		return
+	}
+
+	repo := event.GetRepo()
+	number := event.GetIssue().GetNumber()
+	watches, err := p.getIssueWatches(repo.GetOwner().GetLogin(), repo.GetName(), number)
+	if err != nil {
+		p.API.LogWarn("Failed to get watches", "repo", repo.GetFullName(), "number", number, "error", err.Error())
+		return
+	}
+
+	var unlinked []*Watch
+	for _, w := range watches {
+		if w.RootID != "" {
+			if link, _ := p.getThreadLink(w.RootID); link != nil && strings.EqualFold(link.IssueName(), w.IssueName()) {
+				continue
+			}
+		}
+		unlinked = append(unlinked, w)
+	}
+	if len(unlinked) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("issueComment", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	p.createWatchPosts(repo, unlinked, message)
+}
+
+func (p *Plugin) postPullRequestReviewToWatches(event *github.PullRequestReviewEvent) {
+	if event.GetAction() != actionSubmitted {
+		return
+	}
+
+	message, err := renderTemplate("pullRequestReviewEvent", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	p.postToWatches(event.GetRepo(), event.GetPullRequest().GetNumber(), message)
+}
+
+func (p *Plugin) postPullRequestReviewCommentToWatches(event *github.PullRequestReviewCommentEvent) {
+	if event.GetAction() != actionCreated {
+		return
+	}
+
+	message, err := renderTemplate("newReviewComment", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	p.postToWatches(event.GetRepo(), event.GetPullRequest().GetNumber(), message)
+}
+
+// postCheckSuiteToWatches posts the result of completed checks to the watches of the pull requests they ran for.
+// Check suites of pull requests from forks don't list them, so those are found by the head commit.
+func (p *Plugin) postCheckSuiteToWatches(event *github.CheckSuiteEvent) {
+	if event.GetAction() != actionCompleted {
+		return
+	}
+
+	message, err := renderTemplate("checkSuiteCompleted", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	repo := event.GetRepo()
+	if len(event.GetCheckSuite().PullRequests) == 0 {
+		if number := p.getWatchedHead(repo.GetOwner().GetLogin(), repo.GetName(), event.GetCheckSuite().GetHeadSHA()); number != 0 {
+			p.postToWatches(repo, number, message)
+		}
+		return
+	}
+
+	for _, pr := range event.GetCheckSuite().PullRequests {
+		p.postToWatches(repo, pr.GetNumber(), message)
+	}
+}
+
+// postStatusToWatches posts finished commit statuses to the watches of the pull request whose head is the commit.
+func (p *Plugin) postStatusToWatches(event *github.StatusEvent) {
+	if event.GetState() == "pending" {
+		return
+	}
+
+	repo := event.GetRepo()
+	number := p.getWatchedHead(repo.GetOwner().GetLogin(), repo.GetName(), event.GetSHA())
+	if number == 0 {
+		return
+	}
+
+	message, err := renderTemplate("commitStatus", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	p.postToWatches(repo, number, message)
+}
+
+func (p *Plugin) handleWatch(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please specify an issue or pull request, e.g. `/github watch owner/repo#1`."
+	if len(parameters) == 0 {
+		return usage
+	}
+
+	switch parameters[0] {
+	case "list":
+		return p.handleWatchList(args)
+	case "stop":
+		if len(parameters) != 2 {
+			return "Please specify an issue or pull request, e.g. `/github watch stop owner/repo#1`."
+		}
+		return p.handleWatchStop(args, parameters[1])
+	}
+
+	if len(parameters) != 1 {
+		return usage
+	}
+
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, parameters[0])
+	if err != nil {
+		return usage
+	}
+
+	ctx := context.Background()
+	githubClient := p.githubConnectUser(ctx, userInfo)
+	issue, resp, err := githubClient.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		return "Failed to watch the issue: " + getFailReason(statusCode, fullNameFromOwnerAndRepo(owner, repo), userInfo.GitHubUsername)
+	}
+
+	kind := "issue"
+	if issue.IsPullRequest() {
+		kind = "pull request"
+	}
+
+	watch := &Watch{
+		ChannelID: args.ChannelId,
+		RootID:    args.RootId,
+		CreatorID: args.UserId,
+		Owner:     owner,
+		Repo:      repo,
+		Number:    number,
+	}
+
+	if issue.GetState() == "closed" {
+		return fmt.Sprintf("The %s [%s](%s) is already closed.", kind, watch.IssueName(), watch.IssueURL(p.getBaseURL()))
+	}
+
+	if err := p.AddWatch(watch); err != nil {
+		p.API.LogWarn("Failed to add watch", "issue", watch.IssueName(), "error", err.Error())
+		return "Encountered an error trying to watch the issue. Please try again."
+	}
+
+	if issue.IsPullRequest() {
+		pr, _, err := githubClient.PullRequests.Get(ctx, owner, repo, number)
+		if err != nil {
+			p.API.LogWarn("Failed to fetch pull request", "issue", watch.IssueName(), "error", err.Error())
+		} else {
+			p.storeWatchedHead(owner, repo, pr.GetHead().GetSHA(), number)
+		}
+	}
+
+	target := "channel"
+	if args.RootId != "" {
+		target = "thread"
+	}
+	msg := fmt.Sprintf("Events of the %s [%s](%s) will be posted in this %s until it is closed.", kind, watch.IssueName(), watch.IssueURL(p.getBaseURL()), target)
+
+	ghRepo, _, err := githubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		p.API.LogWarn("Failed to fetch repository", "error", err.Error())
+	} else if ghRepo != nil && ghRepo.GetPrivate() {
+		msg += "\n\n**Warning:** You are watching an issue of a private repository. Anyone with access to this channel will be able to read the events getting posted here."
+	}
+
+	return msg
+}
+
+func (p *Plugin) handleWatchStop(args *model.CommandArgs, ref string) string {
+	owner, repo, number, err := p.parseIssueCommandRef(args.ChannelId, ref)
+	if err != nil {
+		return "Please specify an issue or pull request, e.g. `/github watch stop owner/repo#1`."
+	}
+
+	removed, err := p.RemoveWatch(args.ChannelId, args.RootId, owner, repo, number)
+	if err != nil {
+		p.API.LogWarn("Failed to remove watch", "error", err.Error())
+		return "Encountered an error trying to stop watching the issue. Please try again."
+	}
+
+	target := "channel"
+	if args.RootId != "" {
+		target = "thread"
+	}
+	if !removed {
+		return fmt.Sprintf("This %s is not watching %s#%d.", target, fullNameFromOwnerAndRepo(owner, repo), number)
+	}
+
+	return fmt.Sprintf("This %s is no longer watching %s#%d.", target, fullNameFromOwnerAndRepo(owner, repo), number)
+}
+
+func (p *Plugin) handleWatchList(args *model.CommandArgs) string {
+	watches, err := p.getChannelWatches(args.ChannelId)
+	if err != nil {
+		p.API.LogWarn("Failed to get channel watches", "error", err.Error())
+		return "Failed to get the watches of this channel."
+	}
+
+	if len(watches) == 0 {
+		return "This channel is not watching any issues or pull requests."
+	}
+
+	txt := "### Issues and pull requests watched in this channel\n"
+	for _, w := range watches {
+		txt += fmt.Sprintf("* [%s](%s)", w.IssueName(), w.IssueURL(p.getBaseURL()))
+		if w.RootID != "" {
+			txt += fmt.Sprintf(" in [a thread](%s)", p.getPermaLink(w.RootID))
+		}
+		txt += "\n"
+	}
+
+	return txt
+}
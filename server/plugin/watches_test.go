@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v41/github"
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatches(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	p.SetAPI(api)
+	p.BotUserID = "bot"
+
+	inChannel := &Watch{ChannelID: "channel", CreatorID: "user", Owner: "mattermost", Repo: "mattermost-server", Number: 1}
+	inThread := &Watch{ChannelID: "channel", RootID: "root", CreatorID: "user", Owner: "Mattermost", Repo: "mattermost-server", Number: 1}
+	other := &Watch{ChannelID: "channel", CreatorID: "user", Owner: "mattermost", Repo: "mattermost-server", Number: 2}
+
+	require.NoError(t, p.AddWatch(inChannel))
+	require.NoError(t, p.AddWatch(inThread))
+	require.NoError(t, p.AddWatch(other))
+
+	watches, err := p.getChannelWatches("channel")
+	require.NoError(t, err)
+	assert.Equal(t, []*Watch{inChannel, inThread, other}, watches)
+
+	repo := &github.Repository{Name: sToP("mattermost-server"), FullName: sToP("mattermost/mattermost-server"), Owner: &github.User{Login: sToP("mattermost")}}
+
+	t.Run("comments are posted in the channel and thread", func(t *testing.T) {
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && post.RootId == "" && post.UserId == "bot"
+		})).Return(&model.Post{}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && post.RootId == "root"
+		})).Return(&model.Post{}, nil).Once()
+
+		p.postIssueCommentToWatches(&github.IssueCommentEvent{
+			Action:  sToP(actionCreated),
+			Repo:    repo,
+			Issue:   &github.Issue{Number: iToP(1)},
+			Comment: &github.IssueComment{Body: sToP("Looks good")},
+			Sender:  &github.User{Login: sToP("octocat")},
+		})
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+
+	t.Run("new commits are posted and CI results of forks are matched by head", func(t *testing.T) {
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && post.RootId == "" && strings.Contains(post.Message, "was updated with new commits")
+		})).Return(&model.Post{}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && post.RootId == "" && strings.Contains(post.Message, "Checks on")
+		})).Return(&model.Post{}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && post.RootId == "" && strings.Contains(post.Message, "`ci/jenkins`")
+		})).Return(&model.Post{}, nil).Once()
+
+		p.postPullRequestEventToWatches(&github.PullRequestEvent{
+			Action:      sToP(actionSynchronize),
+			Repo:        repo,
+			PullRequest: &github.PullRequest{Number: iToP(2), Head: &github.PullRequestBranch{SHA: sToP("abc")}},
+			Sender:      &github.User{Login: sToP("octocat")},
+		})
+		p.postCheckSuiteToWatches(&github.CheckSuiteEvent{
+			Action:     sToP(actionCompleted),
+			Repo:       repo,
+			CheckSuite: &github.CheckSuite{HeadSHA: sToP("abc"), Conclusion: sToP("success")},
+		})
+		p.postStatusToWatches(&github.StatusEvent{Repo: repo, SHA: sToP("abc"), State: sToP("pending"), Context: sToP("ci/jenkins")})
+		p.postStatusToWatches(&github.StatusEvent{Repo: repo, SHA: sToP("abc"), State: sToP("success"), Context: sToP("ci/jenkins")})
+		p.postStatusToWatches(&github.StatusEvent{Repo: repo, SHA: sToP("other"), State: sToP("success"), Context: sToP("ci/jenkins")})
+		api.AssertNumberOfCalls(t, "CreatePost", 5)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		removed, err := p.RemoveWatch("channel", "", "mattermost", "mattermost-server", 2)
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		removed, err = p.RemoveWatch("channel", "", "mattermost", "mattermost-server", 2)
+		require.NoError(t, err)
+		assert.False(t, removed)
+
+		watches, err := p.getChannelWatches("channel")
+		require.NoError(t, err)
+		assert.Equal(t, []*Watch{inChannel, inThread}, watches)
+	})
+
+	t.Run("closing ends the watch", func(t *testing.T) {
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel" && assert.Contains(t, post.Message, "This watch has ended.")
+		})).Return(&model.Post{}, nil).Twice()
+
+		p.postIssueEventToWatches(&github.IssuesEvent{
+			Action: sToP(actionClosed),
+			Repo:   repo,
+			Issue:  &github.Issue{Number: iToP(1), Title: sToP("Crash")},
+			Sender: &github.User{Login: sToP("octocat")},
+		})
+		api.AssertNumberOfCalls(t, "CreatePost", 7)
+
+		watches, err := p.getChannelWatches("channel")
+		require.NoError(t, err)
+		assert.Empty(t, watches)
+		assert.Len(t, store, 1, "only the head of the pull request is left to expire")
+	})
+}
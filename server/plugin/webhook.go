@@ -23,10 +23,18 @@ const (
 	actionSubmitted = "submitted"
 	actionLabeled   = "labeled"
 	actionAssigned  = "assigned"
+	actionUnlabeled = "unlabeled"
+	actionCompleted = "completed"
+
+	actionSynchronize     = "synchronize"
+	actionReviewRequested = "review_requested"
 
 	actionCreated = "created"
 	actionDeleted = "deleted"
 	actionEdited  = "edited"
+
+	actionAddedToRepository     = "added_to_repository"
+	actionRemovedFromRepository = "removed_from_repository"
 )
 
 func verifyWebhookSignature(secret []byte, signature string, body []byte) (bool, error) {
@@ -113,74 +121,65 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	var handler func()
 
 	switch event := event.(type) {
+	case *github.OrganizationEvent:
+		p.handleOrganizationEvent(event)
+		return
+	case *github.RepositoryEvent:
+		p.handleRepositoryEvent(event)
+		return
+	case *github.TeamEvent:
+		p.handleTeamEvent(event)
+		return
 	case *github.PullRequestEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postPullRequestEvent(event)
+			p.postPullRequestEventToWatches(event)
 			p.handlePullRequestNotification(event)
 			p.handlePRDescriptionMentionNotification(event)
 		}
 	case *github.IssuesEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postIssueEvent(event)
+			p.postIssueEventToWatches(event)
 			p.handleIssueNotification(event)
 		}
 	case *github.IssueCommentEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postIssueCommentEvent(event)
+			p.postIssueCommentToThreads(event)
+			p.postIssueCommentToWatches(event)
 			p.handleCommentMentionNotification(event)
 			p.handleCommentAuthorNotification(event)
 			p.handleCommentAssigneeNotification(event)
 		}
 	case *github.PullRequestReviewEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postPullRequestReviewEvent(event)
+			p.postPullRequestReviewToWatches(event)
 			p.handlePullRequestReviewNotification(event)
 		}
 	case *github.PullRequestReviewCommentEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postPullRequestReviewCommentEvent(event)
+			p.postPullRequestReviewCommentToWatches(event)
 		}
 	case *github.PushEvent:
 		repo = ConvertPushEventRepositoryToRepository(event.GetRepo())
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postPushEvent(event)
 		}
 	case *github.CreateEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postCreateEvent(event)
 		}
 	case *github.DeleteEvent:
 		repo = event.GetRepo()
-		if p.IsNotificationOff(*repo.FullName) {
-			return
-		}
 		handler = func() {
 			p.postDeleteEvent(event)
 		}
@@ -189,6 +188,16 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		handler = func() {
 			p.postStarEvent(event)
 		}
+	case *github.CheckSuiteEvent:
+		repo = event.GetRepo()
+		handler = func() {
+			p.postCheckSuiteToWatches(event)
+		}
+	case *github.StatusEvent:
+		repo = event.GetRepo()
+		handler = func() {
+			p.postStatusToWatches(event)
+		}
 	}
 
 	if repo == nil || handler == nil {
@@ -216,6 +225,11 @@ func (p *Plugin) permissionToRepo(userID string, ownerAndRepo string) bool {
 		return false
 	}
 
+	cacheKey := strings.ToLower(fullNameFromOwnerAndRepo(owner, repo))
+	if allowed, ok := p.getCachedPermission(userID, cacheKey); ok {
+		return allowed
+	}
+
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		return false
@@ -223,13 +237,18 @@ func (p *Plugin) permissionToRepo(userID string, ownerAndRepo string) bool {
 	ctx := context.Background()
 	githubClient := p.githubConnectUser(ctx, info)
 
-	if result, _, err := githubClient.Repositories.Get(ctx, owner, repo); result == nil || err != nil {
+	if result, resp, err := githubClient.Repositories.Get(ctx, owner, repo); result == nil || err != nil {
 		if err != nil {
 			p.API.LogWarn("Failed fetch repository to check permission", "error", err.Error())
 		}
+		// Only cache definite answers, not transient failures.
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+			p.cachePermission(userID, cacheKey, false)
+		}
 		return false
 	}
 
+	p.cachePermission(userID, cacheKey, true)
 	return true
 }
 
@@ -247,7 +266,7 @@ func (p *Plugin) excludeConfigOrgMember(user *github.User, subscription *Subscri
 	githubClient := p.githubConnectUser(context.Background(), info)
 	organization := p.getConfiguration().GitHubOrg
 
-	return p.isUserOrganizationMember(githubClient, user, organization)
+	return p.isUserOrganizationMember(githubClient, subscription.CreatorID, user, organization)
 }
 
 func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
@@ -282,10 +301,17 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 		return
 	}
 
+	// A description that fails to render only leaves the post without it.
+	prBody, err := renderTemplate("eventBody", pr.GetBody())
+	if err != nil {
+		p.API.LogWarn("Failed to render pull request description", "error", err.Error())
+	}
+
 	post := &model.Post{
 		UserId: p.BotUserID,
 		Type:   "custom_git_pr",
 	}
+	setGitHubObjectProps(post, githubObjectIssue, repo.GetFullName(), pr.GetNumber(), 0)
 
 	for _, sub := range subs {
 		if !sub.Pulls() && !sub.PullsMerged() {
@@ -327,18 +353,23 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 			}
 		}
 
+		attachmentText := post.Message
 		if action == actionOpened {
 			post.Message = p.sanitizeDescription(newPRMessage)
+			attachmentText = p.sanitizeDescription(prBody)
 		}
 
 		if action == actionClosed {
 			post.Message = closedPRMessage
+			attachmentText = closedPRMessage
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
+		attachment := newPullRequestAttachment(repo, pr, event.GetSender(), attachmentText)
+		if action == actionOpened {
+			attachment.Actions = getPullRequestActions(repo, pr)
 		}
+
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 func (p *Plugin) sanitizeDescription(description string) string {
@@ -444,11 +475,23 @@ func (p *Plugin) postIssueEvent(event *github.IssuesEvent) {
 	}
 	renderedMessage = p.sanitizeDescription(renderedMessage)
 
+	attachmentText := renderedMessage
+	if action == actionOpened {
+		// A description that fails to render only leaves the post without it.
+		issueBody, err := renderTemplate("eventBody", issue.GetBody())
+		if err != nil {
+			p.API.LogWarn("Failed to render issue description", "error", err.Error())
+		}
+		attachmentText = p.sanitizeDescription(issueBody)
+	}
+	attachment := newIssueAttachment(repo, issue, event.GetSender(), attachmentText)
+
 	post := &model.Post{
 		UserId:  p.BotUserID,
 		Type:    "custom_git_issue",
 		Message: renderedMessage,
 	}
+	setGitHubObjectProps(post, githubObjectIssue, repo.GetFullName(), issue.GetNumber(), 0)
 
 	eventLabel := event.GetLabel().GetName()
 	labels := make([]string, len(issue.Labels))
@@ -488,10 +531,15 @@ func (p *Plugin) postIssueEvent(event *github.IssuesEvent) {
 			}
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
+		if action == actionOpened {
+			// The triage menus only offer what the creator of the subscription can see.
+			subAttachment := *attachment
+			subAttachment.Actions = getIssueActions(repo, issue, p.getCachedIssueTriageOptions(sub.CreatorID, repo))
+			p.createSubscriptionPost(sub, post, &subAttachment)
+			continue
 		}
+
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -520,6 +568,7 @@ func (p *Plugin) postPushEvent(event *github.PushEvent) {
 		Type:    "custom_git_push",
 		Message: pushedCommitsMessage,
 	}
+	attachment := newEventAttachment(event.GetSender(), pushedCommitsMessage)
 
 	for _, sub := range subs {
 		if !sub.Pushes() {
@@ -530,10 +579,7 @@ func (p *Plugin) postPushEvent(event *github.PushEvent) {
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -561,6 +607,7 @@ func (p *Plugin) postCreateEvent(event *github.CreateEvent) {
 		Type:    "custom_git_create",
 		Message: newCreateMessage,
 	}
+	attachment := newEventAttachment(event.GetSender(), newCreateMessage)
 
 	for _, sub := range subs {
 		if !sub.Creates() {
@@ -571,10 +618,7 @@ func (p *Plugin) postCreateEvent(event *github.CreateEvent) {
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -604,6 +648,7 @@ func (p *Plugin) postDeleteEvent(event *github.DeleteEvent) {
 		Type:    "custom_git_delete",
 		Message: newDeleteMessage,
 	}
+	attachment := newEventAttachment(event.GetSender(), newDeleteMessage)
 
 	for _, sub := range subs {
 		if !sub.Deletes() {
@@ -614,10 +659,7 @@ func (p *Plugin) postDeleteEvent(event *github.DeleteEvent) {
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -644,6 +686,8 @@ func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent) {
 		UserId: p.BotUserID,
 		Type:   "custom_git_comment",
 	}
+	setGitHubObjectProps(post, githubObjectIssueComment, repo.GetFullName(), event.GetIssue().GetNumber(), event.GetComment().GetID())
+	attachment := newIssueAttachment(repo, event.GetIssue(), event.GetSender(), message)
 
 	labels := make([]string, len(event.GetIssue().Labels))
 	for i, v := range event.GetIssue().Labels {
@@ -676,11 +720,7 @@ func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent) {
 			post.Message = message
 		}
 
-		post.ChannelId = sub.ChannelID
-
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -723,6 +763,8 @@ func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent
 		Type:    "custom_git_pull_review",
 		Message: newReviewMessage,
 	}
+	setGitHubObjectProps(post, githubObjectIssue, repo.GetFullName(), event.GetPullRequest().GetNumber(), 0)
+	attachment := newPullRequestAttachment(repo, event.GetPullRequest(), event.GetSender(), newReviewMessage)
 
 	labels := make([]string, len(event.GetPullRequest().Labels))
 	for i, v := range event.GetPullRequest().Labels {
@@ -751,10 +793,7 @@ func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -777,6 +816,8 @@ func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestRevi
 		Type:    "custom_git_pull_review_comment",
 		Message: newReviewMessage,
 	}
+	setGitHubObjectProps(post, githubObjectReviewComment, repo.GetFullName(), event.GetPullRequest().GetNumber(), event.GetComment().GetID())
+	attachment := newPullRequestAttachment(repo, event.GetPullRequest(), event.GetSender(), newReviewMessage)
 
 	labels := make([]string, len(event.GetPullRequest().Labels))
 	for i, v := range event.GetPullRequest().Labels {
@@ -805,10 +846,7 @@ func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestRevi
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
 
@@ -1154,6 +1192,7 @@ func (p *Plugin) postStarEvent(event *github.StarEvent) {
 		Type:    "custom_git_star",
 		Message: newStarMessage,
 	}
+	attachment := newEventAttachment(event.GetSender(), newStarMessage)
 
 	for _, sub := range subs {
 		if !sub.Stars() {
@@ -1164,9 +1203,6 @@ func (p *Plugin) postStarEvent(event *github.StarEvent) {
 			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.createSubscriptionPost(sub, post, attachment)
 	}
 }
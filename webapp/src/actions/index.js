@@ -88,11 +88,11 @@ export function getReviewsDetails(prList) {
     };
 }
 
-export function getRepos() {
+export function getRepos(channelId) {
     return async (dispatch, getState) => {
         let data;
         try {
-            data = await Client.getRepositories();
+            data = await Client.getRepositories(channelId);
         } catch (error) {
             return {error: data};
         }
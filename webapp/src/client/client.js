@@ -43,8 +43,9 @@ export default class Client {
         return this.doPost(`${this.url}/user`, {user_id: userID});
     }
 
-    getRepositories = async () => {
-        return this.doGet(`${this.url}/repositories`);
+    getRepositories = async (channelId) => {
+        const query = channelId ? `?channel_id=${channelId}` : '';
+        return this.doGet(`${this.url}/repositories${query}`);
     }
 
     getLabels = async (repo) => {
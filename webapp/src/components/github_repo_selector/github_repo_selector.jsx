@@ -17,6 +17,7 @@ export default class GithubRepoSelector extends PureComponent {
         theme: PropTypes.object.isRequired,
         onChange: PropTypes.func.isRequired,
         value: PropTypes.string,
+        channelId: PropTypes.string,
         addValidate: PropTypes.func,
         removeValidate: PropTypes.func,
         actions: PropTypes.shape({
@@ -29,8 +30,14 @@ export default class GithubRepoSelector extends PureComponent {
         this.state = initialState;
     }
 
-    componentDidMount() {
-        this.props.actions.getRepos();
+    async componentDidMount() {
+        const {data} = await this.props.actions.getRepos(this.props.channelId);
+
+        // Preselect the default repository of the channel
+        const defaultRepo = data && data.find((r) => r.default);
+        if (defaultRepo && !this.props.value) {
+            this.props.onChange({name: defaultRepo.full_name, permissions: defaultRepo.permissions});
+        }
     }
 
     onChange = (_, name) => {
@@ -181,6 +181,7 @@ export default class CreateIssueModal extends PureComponent {
                 <GithubRepoSelector
                     onChange={this.handleRepoChange}
                     value={this.state.repo && this.state.repo.name}
+                    channelId={this.props.channelId || (this.props.post && this.props.post.channel_id)}
                     required={true}
                     theme={theme}
                     addValidate={this.validator.addComponent}